@@ -0,0 +1,21 @@
+//go:build linux && go1.20
+// +build linux,go1.20
+
+package cmdsync
+
+import (
+	"os"
+	"os/exec"
+)
+
+// placeInCgroup makes cmd start in the cgroup at dir via SysProcAttr, so the
+// process is limited from its first instruction. It needs Linux 5.7 or newer.
+func placeInCgroup(cmd *exec.Cmd, dir string) (placed func(pid int) error, closeCgroup func(), err error) {
+	f, err := os.Open(dir)
+	if err != nil {
+		return nil, nil, err
+	}
+	cmd.SysProcAttr.UseCgroupFD = true
+	cmd.SysProcAttr.CgroupFD = int(f.Fd())
+	return func(int) error { return nil }, func() { f.Close() }, nil
+}
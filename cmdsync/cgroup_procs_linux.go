@@ -0,0 +1,20 @@
+//go:build linux && !go1.20
+// +build linux,!go1.20
+
+package cmdsync
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+)
+
+// placeInCgroup moves the process into the cgroup at dir once it has started,
+// SysProcAttr can only start it there from Go 1.20 on. Processes that it starts
+// before then stay in oneterminal's cgroup.
+func placeInCgroup(cmd *exec.Cmd, dir string) (placed func(pid int) error, closeCgroup func(), err error) {
+	return func(pid int) error {
+		return os.WriteFile(filepath.Join(dir, "cgroup.procs"), []byte(strconv.Itoa(pid)), 0644)
+	}, func() {}, nil
+}
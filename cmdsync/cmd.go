@@ -1,55 +1,85 @@
 // Package cmdsync has logic for synchronizing multiple shell comands.
 // Commands can depend on the completion or readiness of other commands where
-// readiness can be determined by the output matching some regular expression.
+// readiness can be determined by the output matching some regular expression
+// or by a ready check command succeeding.
 package cmdsync
 
 import (
+	"bytes"
 	"context"
 	"fmt"
 	"io"
 	"os"
 	"os/exec"
+	"path/filepath"
 	"regexp"
+	"sort"
 	"strings"
+	"sync"
 	"syscall"
+	"time"
 
 	"github.com/alexchao26/oneterminal/color"
 )
 
 // ShellCmd is a wrapper around exec.Cmd that eases syncing to other ShellCmd's via Group.
 //
-// Its implementation calls the shell directly (through zsh/bash)
+// Its implementation runs the command in a shell, zsh unless another is given,
+// via `<shell> <shell args> <command>` where the shell args default to -c, see
+// ShellArgs. Argv commands run their program directly, without a shell.
 //
 // ShellCmd can indicate that the underlying process has reached a "ready state" by
 //     1. Its stdout/stderr outputs matching a given regexp.
-//     2. Its underlying process completing/exiting with a non-zero code.
+//     2. A ready check command exiting successfully.
+//     3. Its underlying process completing/exiting successfully.
+//
+// Dependents can also wait for other Conditions, such as the process starting.
 //
 // An interrupt signal can be sent to the underlying process via Interrupt().
 type ShellCmd struct {
 	command       *exec.Cmd
+	shell         string
+	shellArgs     []string // passed to the shell before a command
+	argv          []string // run directly instead of in the shell
 	name          string
 	color         color.Color
 	silenceOutput bool
-	ready         bool           // if command's dependent's can begin
-	readyPattern  *regexp.Regexp // pattern to match against command outputs
-	dependsOn     []string       // names of other ShellCmds
-	stdout        io.Writer      // set to os.Stdout, included for testing
+	ready         bool                 // if command's dependent's can begin
+	started       bool                 // if the process has started
+	startedAt     time.Time            // when the process started
+	completed     bool                 // if the process has exited
+	exitErr       error                // of the completed process
+	stopped       bool                 // if a Group stopped the command on purpose
+	readyMut      sync.RWMutex         // guards ready, started, startedAt, completed, exitErr and stopped
+	exited        chan struct{}        // closed once a Group is done running the command
+	stopping      chan struct{}        // closed when stopped is set
+	readyPattern  *regexp.Regexp       // pattern to match against command outputs
+	readyCheck    string               // command that exits successfully once ready
+	environment   map[string]string    // set for the command, its ready check and hooks
+	dependsOn     []string             // names of other ShellCmds
+	conditions    map[string]Condition // of dependsOn that are not ConditionReady
+	stdout        io.Writer            // set to os.Stdout, included for testing
+	watch         *WatchConfig         // files that restart the command in a Group
+	beforeHooks   []string             // commands run before starting in a Group
+	afterHooks    []string             // commands run after exiting in a Group
+	limits        *Limits              // applied before the process execs
+	lateLimits    Limits               // of limits, set once the process has started
+	lastUsage     usageSample          // that the CPU usage is measured against
+	usageMut      sync.Mutex           // guards lastUsage
+	stats         *commandStats        // shared with clones and replacements
 }
 
 type ShellCmdOption func(*ShellCmd) error
 
-// NewShellCmd defaults to using zsh. bash and sh are also supported
+// NewShellCmd runs command via `<shell> -c <command>`. The shell defaults to
+// zsh, any shell that accepts a command this way can be used, e.g. bash, sh,
+// dash, fish, nu or pwsh, by name or by path. ShellArgs replaces the -c.
 func NewShellCmd(shell, command string, options ...ShellCmdOption) (*ShellCmd, error) {
 	if shell == "" {
 		shell = "zsh"
 	}
-	allowedShells := map[string]bool{
-		"zsh":  true,
-		"bash": true,
-		"sh":   true,
-	}
-	if !allowedShells[shell] {
-		return nil, fmt.Errorf("%q shell not supported. Use zsh|bash|sh", shell)
+	if strings.ContainsAny(shell, " \t\n") {
+		return nil, fmt.Errorf("shell %q contains whitespace, pass its arguments with ShellArgs", shell)
 	}
 
 	execCmd := exec.Command(shell, "-c", command)
@@ -58,8 +88,13 @@ func NewShellCmd(shell, command string, options ...ShellCmdOption) (*ShellCmd, e
 	execCmd.SysProcAttr = &syscall.SysProcAttr{Setpgid: true}
 
 	s := &ShellCmd{
-		command: execCmd,
-		stdout:  os.Stdout,
+		command:   execCmd,
+		shell:     shell,
+		shellArgs: []string{"-c"},
+		stdout:    os.Stdout,
+		exited:    make(chan struct{}),
+		stopping:  make(chan struct{}),
+		stats:     &commandStats{},
 	}
 
 	// apply functional options
@@ -70,12 +105,41 @@ func NewShellCmd(shell, command string, options ...ShellCmdOption) (*ShellCmd, e
 		}
 	}
 
-	execCmd.Stdout = s
-	execCmd.Stderr = s
+	if len(s.environment) > 0 && (s.argv != nil || !exportsEnvironment(shell)) {
+		execCmd.Env = os.Environ()
+		for _, k := range sortedKeys(s.environment) {
+			execCmd.Env = append(execCmd.Env, fmt.Sprintf("%s=%s", k, os.ExpandEnv(s.environment[k])))
+		}
+	}
+
+	if s.argv != nil {
+		argvCmd := exec.Command(s.argv[0], s.argv[1:]...)
+		argvCmd.Dir = execCmd.Dir
+		argvCmd.Env = execCmd.Env
+		argvCmd.SysProcAttr = execCmd.SysProcAttr
+		s.command = argvCmd
+	} else {
+		execCmd.Args = append([]string{shell}, append(s.shellArgs, s.exportPrefix()+command)...)
+	}
+	if s.limits != nil {
+		s.lateLimits = limitCommand(s.command, *s.limits)
+	}
+	s.command.Stdout = s
+	s.command.Stderr = s
 
 	return s, nil
 }
 
+// shellCmd makes an exec.Cmd that runs script in the ShellCmd's shell,
+// directory and environment, e.g. for ready checks
+func (s *ShellCmd) shellCmd(ctx context.Context, script string) *exec.Cmd {
+	args := append(append([]string{}, s.shellArgs...), s.exportPrefix()+script)
+	cmd := exec.CommandContext(ctx, s.shell, args...)
+	cmd.Dir = s.command.Dir
+	cmd.Env = s.command.Env
+	return cmd
+}
+
 // Run the underlying command. This function blocks until the command exits
 func (s *ShellCmd) Run() error {
 	return s.RunContext(context.Background())
@@ -83,17 +147,37 @@ func (s *ShellCmd) Run() error {
 
 // RunContext is the same as Run but cancels if the ctx cancels
 func (s *ShellCmd) RunContext(ctx context.Context) error {
+	started, cleanup := func() {}, func() {}
+	if s.limits != nil {
+		started, cleanup = s.applyLimits()
+	}
 	// start the command's execution
 	if err := s.command.Start(); err != nil {
+		cleanup()
+		s.setCompleted(err)
 		return fmt.Errorf("failed to start command: %w", err)
 	}
+	started()
+	s.setStarted()
+	// a Group may have stopped the command while it was starting
+	if s.isStopped() {
+		s.Interrupt()
+	}
 
 	// make waiting for cmd to run concurrent so select can be used
 	done := make(chan error, 1)
 	go func() {
 		done <- s.command.Wait()
+		s.stats.addExit(s.command.ProcessState)
+		cleanup()
 	}()
 
+	if s.readyCheck != "" {
+		checkCtx, cancel := context.WithCancel(ctx)
+		defer cancel()
+		go s.pollReadyCheck(checkCtx)
+	}
+
 	var err error
 	// blocks until underlying process is done/exits or ctx is done
 	select {
@@ -103,10 +187,31 @@ func (s *ShellCmd) RunContext(ctx context.Context) error {
 	case doneErr := <-done:
 		err = doneErr
 	}
-	s.ready = true
+	s.setCompleted(err)
 	return err
 }
 
+// pollReadyCheck runs the ready check command every second until it exits
+// successfully, the ShellCmd becomes ready some other way, or ctx is done
+func (s *ShellCmd) pollReadyCheck(ctx context.Context) {
+	ticker := time.NewTicker(readyCheckInterval)
+	defer ticker.Stop()
+	for !s.IsReady() {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		if s.shellCmd(ctx, s.readyCheck).Run() == nil {
+			s.setReady()
+		}
+	}
+}
+
+// readyCheckInterval is how often a ready check command is run
+const readyCheckInterval = time.Second
+
 // Interrupt will send an interrupt signal to the process
 func (s *ShellCmd) Interrupt() error {
 	// Process is not set if it has not been started yet
@@ -129,8 +234,9 @@ func (s *ShellCmd) Interrupt() error {
 // regexp and determines if a command has reached its "ready state"
 // the ready state is used by Orchestrator to coordinate dependent commands
 func (s *ShellCmd) Write(in []byte) (int, error) {
+	s.stats.addLines(bytes.Count(in, []byte("\n")))
 	if s.readyPattern != nil && s.readyPattern.Match(in) {
-		s.ready = true
+		s.setReady()
 	}
 
 	if s.silenceOutput {
@@ -149,6 +255,16 @@ func (s *ShellCmd) Write(in []byte) (int, error) {
 	return len(in), err
 }
 
+// printStatus prints a message about the ShellCmd itself rather than its
+// output, even if its output is silenced
+func (s *ShellCmd) printStatus(msg string) {
+	msg += "\n"
+	if s.name != "" {
+		msg = prefixEveryline(msg, s.color.Add(s.name))
+	}
+	fmt.Fprint(s.stdout, msg)
+}
+
 // prefixEachLine adds a given prefix with a bar/pipe " | " to each newline
 func prefixEveryline(in, prefix string) (out string) {
 	lines := strings.Split(in, "\n")
@@ -162,18 +278,134 @@ func prefixEveryline(in, prefix string) (out string) {
 
 // IsReady is a simple getter for the ready state of a monitored command
 func (s *ShellCmd) IsReady() bool {
+	s.readyMut.RLock()
+	defer s.readyMut.RUnlock()
 	return s.ready
 }
 
+func (s *ShellCmd) setReady() {
+	s.readyMut.Lock()
+	defer s.readyMut.Unlock()
+	s.ready = true
+}
+
+func (s *ShellCmd) setStarted() {
+	s.readyMut.Lock()
+	defer s.readyMut.Unlock()
+	s.started = true
+	s.startedAt = time.Now()
+}
+
+// setCompleted records how the process exited, it is ready if it succeeded
+func (s *ShellCmd) setCompleted(err error) {
+	s.readyMut.Lock()
+	defer s.readyMut.Unlock()
+	s.completed = true
+	s.exitErr = err
+	if err == nil {
+		s.ready = true
+	}
+}
+
+// meets returns true if the ShellCmd has reached condition
+func (s *ShellCmd) meets(condition Condition) bool {
+	s.readyMut.RLock()
+	defer s.readyMut.RUnlock()
+	switch condition {
+	case ConditionStarted:
+		return s.started
+	case ConditionCompleted:
+		return s.completed
+	case ConditionCompletedSuccessfully:
+		return s.completed && s.exitErr == nil
+	default:
+		return s.ready
+	}
+}
+
+// stop marks the command as stopped on purpose and interrupts it if it is
+// running, a Group will not start a stopped command
+func (s *ShellCmd) stop() {
+	s.readyMut.Lock()
+	if !s.stopped {
+		s.stopped = true
+		close(s.stopping)
+	}
+	s.readyMut.Unlock()
+	s.Interrupt()
+}
+
+func (s *ShellCmd) isStopped() bool {
+	s.readyMut.RLock()
+	defer s.readyMut.RUnlock()
+	return s.stopped
+}
+
+// clone returns a ShellCmd with the same options that has not been run yet, as
+// an exec.Cmd can only be run once
+func (s *ShellCmd) clone() *ShellCmd {
+	execCmd := exec.Command(s.command.Path)
+	execCmd.Args = s.command.Args
+	execCmd.Dir = s.command.Dir
+	execCmd.Env = s.command.Env
+	execCmd.SysProcAttr = &syscall.SysProcAttr{Setpgid: true}
+
+	c := &ShellCmd{
+		command:       execCmd,
+		shell:         s.shell,
+		shellArgs:     s.shellArgs,
+		argv:          s.argv,
+		name:          s.name,
+		color:         s.color,
+		silenceOutput: s.silenceOutput,
+		exited:        make(chan struct{}),
+		stopping:      make(chan struct{}),
+		readyPattern:  s.readyPattern,
+		readyCheck:    s.readyCheck,
+		environment:   s.environment,
+		dependsOn:     s.dependsOn,
+		conditions:    s.conditions,
+		stdout:        s.stdout,
+		watch:         s.watch,
+		beforeHooks:   s.beforeHooks,
+		afterHooks:    s.afterHooks,
+		limits:        s.limits,
+		lateLimits:    s.lateLimits,
+		stats:         s.stats,
+	}
+	execCmd.Stdout = c
+	execCmd.Stderr = c
+	return c
+}
+
+// ShellArgs is a functional option that sets the arguments passed to the shell
+// before the command, and before ready check and hook commands. It defaults to
+// -c, e.g. use ShellArgs("-NoProfile", "-Command") for pwsh.
+func ShellArgs(args ...string) ShellCmdOption {
+	return func(s *ShellCmd) error {
+		s.shellArgs = args
+		return nil
+	}
+}
+
+// Argv is a functional option that runs a program with exactly the given
+// arguments instead of running the command in a shell, so nothing is expanded
+// or split. The shell still runs ready check and hook commands.
+func Argv(argv ...string) ShellCmdOption {
+	return func(s *ShellCmd) error {
+		if len(argv) == 0 || argv[0] == "" {
+			return fmt.Errorf("argv needs a program to run")
+		}
+		s.argv = argv
+		return nil
+	}
+}
+
 // CmdDir is a functional option that modifies the Dir property of the
 // underlying exec.ShellCmd which is the directory to execute the Command from
 func CmdDir(dir string) ShellCmdOption {
 	return func(s *ShellCmd) error {
-		// expand '~' to $HOME for os.ExpandEnv to pickup
-		if dir[0] == '~' {
-			dir = fmt.Sprintf("$HOME%s", dir[1:])
-		}
-		expandedDir := os.ExpandEnv(dir)
+		expandedDir := ExpandDir(dir)
 
 		_, err := os.Stat(expandedDir)
 		if os.IsNotExist(err) {
@@ -185,6 +417,16 @@ func CmdDir(dir string) ShellCmdOption {
 	}
 }
 
+// ExpandDir expands a leading '~' and environment variables in a directory the
+// same way as the CmdDir option
+func ExpandDir(dir string) string {
+	// expand '~' to $HOME for os.ExpandEnv to pickup
+	if dir != "" && dir[0] == '~' {
+		dir = fmt.Sprintf("$HOME%s", dir[1:])
+	}
+	return os.ExpandEnv(dir)
+}
+
 // SilenceOutput sets the command's Stdout and Stderr to nil so no output
 // will be seen in the terminal
 func SilenceOutput() ShellCmdOption {
@@ -225,6 +467,17 @@ func ReadyPattern(pattern string) ShellCmdOption {
 	}
 }
 
+// ReadyCheck is a functional option that sets a command that is run in the same
+// shell, directory and environment every second once the ShellCmd has started.
+// The ShellCmd is ready as soon as the check exits successfully, e.g.
+//   cmdsync.ReadyCheck("curl -sf localhost:8080/health")
+func ReadyCheck(command string) ShellCmdOption {
+	return func(s *ShellCmd) error {
+		s.readyCheck = command
+		return nil
+	}
+}
+
 // DependsOn is a functional option that sets a slice of dependencies for this
 // command. The dependencies are names of commands that need to have completed
 // or reached a ready state prior to this command starting.
@@ -242,17 +495,98 @@ func DependsOn(cmdNames ...string) ShellCmdOption {
 	}
 }
 
+// Condition is the state a dependency has to reach before the ShellCmds that
+// depend on it start, mirroring docker compose's depends_on conditions
+type Condition string
+
+const (
+	// ConditionReady is met once the dependency's ready pattern or ready check
+	// matches, or once it exits successfully. It is the default condition.
+	ConditionReady Condition = "ready"
+	// ConditionStarted is met as soon as the dependency's process has started
+	ConditionStarted Condition = "started"
+	// ConditionCompletedSuccessfully is met once the dependency has exited
+	// successfully, e.g. for migrations
+	ConditionCompletedSuccessfully Condition = "completed-successfully"
+	// ConditionCompleted is met once the dependency has exited, even if it
+	// failed. A Group keeps running when a dependency with this condition fails.
+	ConditionCompleted Condition = "completed"
+)
+
+// DependsOnCondition is a functional option that adds a dependency that has to
+// reach condition before this command starts. Dependencies added with DependsOn
+// use ConditionReady.
+func DependsOnCondition(cmdName string, condition Condition) ShellCmdOption {
+	return func(s *ShellCmd) error {
+		switch condition {
+		case ConditionReady, ConditionStarted, ConditionCompletedSuccessfully, ConditionCompleted:
+		default:
+			return fmt.Errorf("unknown condition %q for dependency %q", condition, cmdName)
+		}
+		s.dependsOn = append(s.dependsOn, cmdName)
+		if condition != ConditionReady {
+			if s.conditions == nil {
+				s.conditions = make(map[string]Condition)
+			}
+			s.conditions[cmdName] = condition
+		}
+		return nil
+	}
+}
+
+// condition returns the condition that the dependency named depName has to
+// reach before s starts
+func (s *ShellCmd) condition(depName string) Condition {
+	if condition, ok := s.conditions[depName]; ok {
+		return condition
+	}
+	return ConditionReady
+}
+
 // Environment is a functional option that adds export commands to the start
-// of a command. This is a bit of a hacky workaround to maintain exec.ShellCmd's
-// default environment, while being able to set additional variables
+// of a command, and of its ready check and hooks. The shell expands the values
+// like any other, e.g. $HOME/go or $(git rev-parse HEAD). Shells without
+// export, e.g. pwsh, and Argv commands get the variables added to their
+// environment instead, where only environment variables are expanded.
 func Environment(envMap map[string]string) ShellCmdOption {
+	return func(s *ShellCmd) error {
+		if s.environment == nil {
+			s.environment = make(map[string]string, len(envMap))
+		}
+		for k, v := range envMap {
+			s.environment[k] = v
+		}
+		return nil
+	}
+}
+
+// exportShells are the shells that Environment uses export commands for
+var exportShells = map[string]bool{
+	"sh": true, "bash": true, "zsh": true, "dash": true, "ksh": true, "mksh": true, "ash": true,
+}
+
+func exportsEnvironment(shell string) bool {
+	return exportShells[filepath.Base(shell)]
+}
+
+// exportPrefix returns the export commands that set the environment before a
+// script, unless it is set on the exec.Cmd instead
+func (s *ShellCmd) exportPrefix() string {
+	if len(s.environment) == 0 || s.command.Env != nil {
+		return ""
+	}
 	var exportVars string
-	for k, v := range envMap {
-		exportVars += fmt.Sprintf("export %s=%s && ", k, v)
+	for _, k := range sortedKeys(s.environment) {
+		exportVars += fmt.Sprintf("export %s=%s && ", k, s.environment[k])
 	}
+	return exportVars
+}
 
-	return func(s *ShellCmd) error {
-		s.command.Args[2] = exportVars + s.command.Args[2]
-		return nil
+func sortedKeys(m map[string]string) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
 	}
+	sort.Strings(keys)
+	return keys
 }
@@ -63,6 +63,17 @@ func TestShellCmd_Run(t *testing.T) {
 			wantOutput: "beepboop\n",
 			wantError:  nil,
 		},
+		{
+			name:    "environment values are expanded by the shell",
+			command: "echo $TEST_ENV_VAR",
+			commandOpts: []ShellCmdOption{
+				Environment(map[string]string{
+					"TEST_ENV_VAR": "$(echo beep)boop",
+				}),
+			},
+			wantOutput: "beepboop\n",
+			wantError:  nil,
+		},
 		{
 			name:    "name prefixes output line",
 			command: "echo potato",
@@ -86,6 +97,35 @@ func TestShellCmd_Run(t *testing.T) {
 			wantOutput: "NAME | hello\n",
 			wantError:  errors.New("exit status 1"),
 		},
+		{
+			name:    "shell args are passed before the command",
+			command: "false; echo unreachable",
+			commandOpts: []ShellCmdOption{
+				ShellArgs("-e", "-c"),
+			},
+			wantError: errors.New("exit status 1"),
+		},
+		{
+			name:    "argv is not expanded by the shell",
+			command: "",
+			commandOpts: []ShellCmdOption{
+				Argv("echo", "$HOME *", "it's"),
+			},
+			wantOutput: "$HOME * it's\n",
+			wantError:  nil,
+		},
+		{
+			name:    "argv gets environment with variables expanded",
+			command: "",
+			commandOpts: []ShellCmdOption{
+				Argv("printenv", "TEST_ENV_VAR"),
+				Environment(map[string]string{
+					"TEST_ENV_VAR": "$TEST_UNSET_VAR/beepboop",
+				}),
+			},
+			wantOutput: "/beepboop\n",
+			wantError:  nil,
+		},
 	}
 
 	// test all installed and supported shells
@@ -119,6 +159,9 @@ func TestShellCmd_Run(t *testing.T) {
 				}
 
 				output := sb.String()
+				if tt.wantOutput == "" && tt.wantOutputToContain == nil && output != "" {
+					t.Errorf("shCmd.Run() want no output, got %q", output)
+				}
 				if tt.wantOutput != "" && tt.wantOutput != output {
 					t.Errorf("shCmd.Run() want %q, got %q", tt.wantOutput, output)
 				}
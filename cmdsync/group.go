@@ -13,9 +13,15 @@ import (
 
 // Group manages scheduling concurrent ShellCmds
 type Group struct {
-	commands   []*ShellCmd
-	hasStarted bool
-	mut        sync.RWMutex
+	commands    []*ShellCmd
+	beforeHooks []*ShellCmd
+	afterHooks  []*ShellCmd
+	hasStarted  bool
+	mut         sync.RWMutex
+
+	// set while running, starts a command in the running group
+	start   func(cmd *ShellCmd, replaces *ShellCmd)
+	running int // number of commands that have not returned yet
 }
 
 // NewGroup makes a new Group
@@ -39,10 +45,27 @@ func (g *Group) AddCommands(commands ...*ShellCmd) error {
 	return nil
 }
 
+// AddHooks adds ShellCmds that run one after another before the Group's
+// commands start, and after all of them have exited. The commands do not start
+// if a before hook fails, but the after hooks always run, even if the Group was
+// interrupted.
+// It will return an error if called after Group.Run()
+func (g *Group) AddHooks(before, after []*ShellCmd) error {
+	g.mut.Lock()
+	defer g.mut.Unlock()
+	if g.hasStarted {
+		return fmt.Errorf("Group has already been started")
+	}
+	g.beforeHooks = append(g.beforeHooks, before...)
+	g.afterHooks = append(g.afterHooks, after...)
+	return nil
+}
+
 // Run will run all of the group's ShellCmds and block until they have all
 // finished running or an interrupt signal is sent (ctrl + c). Internally it
 // relays the first interrupt signal to all underlying ShellCmds. Additional
-// interrupt commands will return to normal behavior.
+// interrupt commands will return to normal behavior, unless the Group or its
+// ShellCmds have after hooks, which are given time to clean up.
 //
 // It checks for each ShellCmd's prerequisites before starting. See ShellCmd for
 // details on ready regexp.
@@ -72,59 +95,287 @@ func (g *Group) Run() error {
 //   }()
 //   err := group.Run(ctx)
 //   // handle error
+//
+// Once the context is cancelled, interrupt signals are ignored until the after
+// hooks have run, if there are any.
 func (g *Group) RunContext(ctx context.Context) error {
-	g.mut.Lock()
-	g.hasStarted = true
-	g.mut.Unlock()
-
-	namesToCmds := make(map[string]*ShellCmd, len(g.commands))
-	for _, cmd := range g.commands {
-		namesToCmds[cmd.name] = cmd
+	// stop waiting for the before hooks if the context is cancelled
+	if err := runBeforeHooks(ctx, g.beforeHooks); err != nil {
+		// the failure may be a ctrl + c, which a second one must not cut short
+		if len(g.afterHooks) > 0 {
+			defer ignoreInterrupts()()
+		}
+		runAfterHooks(g.afterHooks)
+		return err
 	}
 
 	eg, ctx := errgroup.WithContext(ctx)
 
+	shutdown := make(chan struct{})
+	restoreInterrupts := func() {}
 	go func() {
 		<-ctx.Done()
+		if g.hasAfterHooks() {
+			// keep a second ctrl + c from killing oneterminal mid cleanup
+			restoreInterrupts = ignoreInterrupts()
+		}
+		close(shutdown)
 		g.SendInterrupts()
 	}()
+	defer func() { restoreInterrupts() }()
 
-	for _, cmd := range g.commands {
-		// https://github.com/golang/go/wiki/CommonMistakes#using-goroutines-on-loop-iterator-variables
-		cmd := cmd
+	g.mut.Lock()
+	g.hasStarted = true
+	g.start = func(cmd *ShellCmd, replaces *ShellCmd) {
+		// running is decremented before the errgroup is done with the command,
+		// so commands can only be started while eg.Wait() is still blocking
+		g.running++
 		eg.Go(func() error {
-			ticker := time.NewTicker(time.Millisecond * 200)
-			defer ticker.Stop()
-			// on every tick, exit if context is done (shutdown has started)
-			// then start command if all depends-on ShellCmds' are in a ready state
-			for {
-				select {
-				case <-ctx.Done():
-					return ctx.Err()
-				case <-ticker.C:
-				}
+			defer func() {
+				g.mut.Lock()
+				g.running--
+				g.mut.Unlock()
+			}()
+			return g.runCommand(ctx, cmd, replaces)
+		})
+	}
+	for _, cmd := range g.commands {
+		g.start(cmd, nil)
+	}
+	g.mut.Unlock()
 
-				canStart, err := checkDependencies(cmd, namesToCmds)
-				if err != nil {
-					return fmt.Errorf("%s: %w", cmd.name, err)
+	err := eg.Wait()
+	// eg.Wait cancels ctx, so shutdown is closed shortly
+	<-shutdown
+	runAfterHooks(g.afterHooks)
+	return err
+}
+
+// ignoreInterrupts keeps interrupt signals from killing the process until the
+// returned function is called
+func ignoreInterrupts() func() {
+	interrupts := make(chan os.Signal, 1)
+	signal.Notify(interrupts, os.Interrupt)
+	return func() { signal.Stop(interrupts) }
+}
+
+// hasAfterHooks returns true if the Group or any of its commands have after
+// hooks
+func (g *Group) hasAfterHooks() bool {
+	g.mut.RLock()
+	defer g.mut.RUnlock()
+	if len(g.afterHooks) > 0 {
+		return true
+	}
+	for _, cmd := range g.commands {
+		if len(cmd.afterHooks) > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// runCommand starts cmd once the command it replaces, if any, has exited and
+// all of cmd's dependencies are ready. It blocks until cmd exits, or for
+// watched commands until cmd is restarted
+func (g *Group) runCommand(ctx context.Context, cmd *ShellCmd, replaces *ShellCmd) error {
+	defer close(cmd.exited)
+	if replaces != nil {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-replaces.exited:
+		}
+	}
+
+	if cmd.watch != nil {
+		watchCtx, cancel := context.WithCancel(ctx)
+		defer cancel()
+		changes, err := cmd.watchChanges(watchCtx)
+		if err != nil {
+			return fmt.Errorf("%s: %w", cmd.name, err)
+		}
+		go func() {
+			for range changes {
+				g.restart(cmd)
+			}
+		}()
+	}
+
+	ticker := time.NewTicker(time.Millisecond * 200)
+	defer ticker.Stop()
+	// on every tick, exit if context is done (shutdown has started)
+	// then start command if all depends-on ShellCmds' are in a ready state
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+		if cmd.isStopped() {
+			return nil
+		}
+
+		canStart, err := g.checkDependencies(cmd)
+		if err != nil {
+			return fmt.Errorf("%s: %w", cmd.name, err)
+		}
+		if canStart {
+			ticker.Stop()
+			err := runBeforeHooks(ctx, cmd.hooks("before", cmd.beforeHooks))
+			if err != nil {
+				// dependents waiting for cmd to complete do not wait forever
+				cmd.setCompleted(err)
+			} else {
+				err = cmd.Run()
+			}
+			runAfterHooks(cmd.hooks("after", cmd.afterHooks))
+			// commands that are stopped by Replace or Remove exit on purpose
+			if cmd.isStopped() {
+				return nil
+			}
+			if cmd.watch != nil && ctx.Err() == nil {
+				return cmd.waitForRestart(ctx, err)
+			}
+			if err != nil && ctx.Err() == nil && g.allowedToFail(cmd) {
+				// dependents start regardless, so the Group keeps running
+				cmd.printStatus(fmt.Sprintf("exited with %v", err))
+				return nil
+			}
+			if err != nil {
+				return fmt.Errorf("%s: %w", cmd.name, err)
+			}
+			return nil
+		}
+	}
+}
+
+// Replace stops the command with the same name as cmd and runs cmd in its place
+// once the old command has exited and cmd's dependencies are ready. If no
+// command has cmd's name, cmd is added to the Group. Commands that depend on
+// the replaced command keep running.
+//
+// Replace can be called before or while the Group is running, but returns an
+// error once all of the Group's commands have exited.
+func (g *Group) Replace(cmd *ShellCmd) error {
+	g.mut.Lock()
+	defer g.mut.Unlock()
+	if g.hasStarted && g.running == 0 {
+		return fmt.Errorf("Group has already finished")
+	}
+	g.replace(cmd)
+	return nil
+}
+
+// replace swaps cmd in for the command with the same name, g.mut must be held
+func (g *Group) replace(cmd *ShellCmd) {
+	var replaced *ShellCmd
+	for i, c := range g.commands {
+		if c.name == cmd.name {
+			replaced = c
+			g.commands[i] = cmd
+			break
+		}
+	}
+	if replaced == nil {
+		g.commands = append(g.commands, cmd)
+	} else {
+		replaced.stop()
+		cmd.stats = replaced.stats
+		if g.hasStarted {
+			cmd.stats.addRestart()
+		}
+	}
+
+	if g.hasStarted {
+		g.start(cmd, replaced)
+	}
+}
+
+// Restart stops the command with the given name and runs it again once it has
+// exited and its dependencies are ready. If dependents is true, the commands
+// that depend on it, directly or through other commands, are restarted too and
+// wait for it to be ready again.
+//
+// Like Replace, Restart returns an error once all of the Group's commands have
+// exited.
+func (g *Group) Restart(name string, dependents bool) error {
+	g.mut.Lock()
+	defer g.mut.Unlock()
+	if g.hasStarted && g.running == 0 {
+		return fmt.Errorf("Group has already finished")
+	}
+	for _, c := range g.commands {
+		if c.name == name {
+			g.restartLocked(c, dependents)
+			return nil
+		}
+	}
+	return fmt.Errorf("command %q does not exist", name)
+}
+
+// restart restarts a watched command after its files changed, unless it has
+// been replaced or removed in the meantime
+func (g *Group) restart(cmd *ShellCmd) {
+	g.mut.Lock()
+	defer g.mut.Unlock()
+	for _, c := range g.commands {
+		if c == cmd {
+			g.restartLocked(cmd, cmd.watch.RestartDependents)
+			return
+		}
+	}
+}
+
+// restartLocked replaces cmd, and optionally its dependents, with clones of
+// themselves, g.mut must be held
+func (g *Group) restartLocked(cmd *ShellCmd, dependents bool) {
+	restarts := []*ShellCmd{cmd}
+	if dependents {
+		restarted := map[string]bool{cmd.name: true}
+		// repeat until no more commands depend on restarted commands
+		for found := true; found; {
+			found = false
+			for _, c := range g.commands {
+				if restarted[c.name] {
+					continue
 				}
-				if canStart {
-					ticker.Stop()
-					err := cmd.Run()
-					if err != nil {
-						return fmt.Errorf("%s: %w", cmd.name, err)
+				for _, dep := range c.dependsOn {
+					if restarted[dep] {
+						restarted[c.name] = true
+						restarts = append(restarts, c)
+						found = true
+						break
 					}
-					return nil
 				}
 			}
-		})
+		}
+	}
+	for _, c := range restarts {
+		g.replace(c.clone())
 	}
+}
 
-	return eg.Wait()
+// Remove stops the command with the given name and removes it from the Group.
+// Commands that depend on it keep running, but commands that have not started
+// yet will error.
+func (g *Group) Remove(name string) error {
+	g.mut.Lock()
+	defer g.mut.Unlock()
+	for i, c := range g.commands {
+		if c.name == name {
+			g.commands = append(g.commands[:i], g.commands[i+1:]...)
+			c.stop()
+			return nil
+		}
+	}
+	return fmt.Errorf("command %q does not exist", name)
 }
 
 // SendInterrupts relays an interrupt signal to all underlying commands
 func (g *Group) SendInterrupts() {
+	g.mut.RLock()
+	defer g.mut.RUnlock()
 	if !g.hasStarted {
 		return
 	}
@@ -133,18 +384,47 @@ func (g *Group) SendInterrupts() {
 	}
 }
 
-func checkDependencies(cmd *ShellCmd, allCmdsMap map[string]*ShellCmd) (bool, error) {
+func (g *Group) checkDependencies(cmd *ShellCmd) (bool, error) {
+	g.mut.RLock()
+	defer g.mut.RUnlock()
 	for _, depName := range cmd.dependsOn {
-		depCmd, ok := allCmdsMap[depName]
-		if !ok {
-			return false, fmt.Errorf("%q depends-on %q, but %q does not exist", cmd.name, depName, depName)
-		}
 		if cmd.name == depName {
 			return false, fmt.Errorf("%s depends on itself", cmd.name)
 		}
-		if !depCmd.IsReady() {
+		var depCmd *ShellCmd
+		for _, c := range g.commands {
+			if c.name == depName {
+				depCmd = c
+				break
+			}
+		}
+		if depCmd == nil {
+			return false, fmt.Errorf("%q depends-on %q, but %q does not exist", cmd.name, depName, depName)
+		}
+		if !depCmd.meets(cmd.condition(depName)) {
 			return false, nil
 		}
 	}
 	return true, nil
 }
+
+// allowedToFail returns true if cmd has dependents and all of them depend on it
+// with ConditionCompleted. Dependents waiting for any other condition would
+// never start once cmd has failed, so the Group fails instead.
+func (g *Group) allowedToFail(cmd *ShellCmd) bool {
+	g.mut.RLock()
+	defer g.mut.RUnlock()
+	awaited := false
+	for _, c := range g.commands {
+		for _, depName := range c.dependsOn {
+			if depName != cmd.name {
+				continue
+			}
+			if c.condition(depName) != ConditionCompleted {
+				return false
+			}
+			awaited = true
+		}
+	}
+	return awaited
+}
@@ -62,3 +62,25 @@ func ExampleGroup_RunContext_notify() {
 	// second | are
 	// third | great
 }
+
+func ExampleReadyCheck() {
+	api, _ := cmdsync.NewShellCmd("bash", "sleep 1.5 && echo shutting down",
+		cmdsync.Name("api"),
+		// run every second until it succeeds, e.g. curl -sf localhost:8080/health
+		cmdsync.ReadyCheck("true"),
+	)
+	client, _ := cmdsync.NewShellCmd("bash", "echo api is up",
+		cmdsync.Name("client"),
+		// starts once the ready check succeeded, while api keeps running
+		cmdsync.DependsOn("api"),
+	)
+
+	group := cmdsync.NewGroup(api, client)
+	err := group.Run()
+	if err != nil {
+		log.Fatal(err)
+	}
+	// Output:
+	// client | api is up
+	// api | shutting down
+}
@@ -3,9 +3,12 @@ package cmdsync
 import (
 	"context"
 	"errors"
+	"fmt"
 	"os"
 	"strings"
+	"sync"
 	"testing"
+	"time"
 )
 
 func TestGroup_RunContext(t *testing.T) {
@@ -70,6 +73,19 @@ func TestGroup_RunContext(t *testing.T) {
 			wantOutput: "first | monkeypotato\nsecond | next\nlast | last\nfirst | finally\n",
 			wantError:  nil,
 		},
+		{
+			name: "ready check allows dependent commands to start concurrently",
+			group: NewGroup(
+				mustNewShellCmd(testShell, "sleep 3 && echo finally",
+					Name("first"),
+					ReadyCheck("test -n \"$READY\""),
+					Environment(map[string]string{"READY": "yes"}),
+				),
+				mustNewShellCmd(testShell, "echo next", Name("second"), DependsOn("first")),
+			),
+			wantOutput: "second | next\nfirst | finally\n",
+			wantError:  nil,
+		},
 		{
 			name: "test with echo, cat and rm commands",
 			group: NewGroup(
@@ -106,6 +122,43 @@ func TestGroup_RunContext(t *testing.T) {
 			wantOutput: "",
 			wantError:  context.Canceled,
 		},
+		{
+			name: "a failed dependency is not ready",
+			group: NewGroup(
+				mustNewShellCmd(testShell, "exit 1", Name("migrate")),
+				mustNewShellCmd(testShell, "echo started", Name("api"), DependsOn("migrate")),
+			),
+			wantOutput: "",
+			wantError:  errors.New("migrate: exit status 1"),
+		},
+		{
+			name: "started condition does not wait for ready",
+			group: NewGroup(
+				mustNewShellCmd(testShell, "sleep 1 && echo ready", Name("db"), ReadyPattern("ready")),
+				mustNewShellCmd(testShell, "echo started", Name("api"), DependsOnCondition("db", ConditionStarted)),
+			),
+			wantOutput: "api | started\ndb | ready\n",
+			wantError:  nil,
+		},
+		{
+			name: "completed-successfully condition waits for exit",
+			group: NewGroup(
+				mustNewShellCmd(testShell, "echo ready && sleep 1 && echo done", Name("migrate"), ReadyPattern("ready")),
+				mustNewShellCmd(testShell, "echo started", Name("api"),
+					DependsOnCondition("migrate", ConditionCompletedSuccessfully)),
+			),
+			wantOutput: "migrate | ready\nmigrate | done\napi | started\n",
+			wantError:  nil,
+		},
+		{
+			name: "completed condition allows failures",
+			group: NewGroup(
+				mustNewShellCmd(testShell, "echo cleaning && exit 3", Name("cleanup")),
+				mustNewShellCmd(testShell, "echo started", Name("api"), DependsOnCondition("cleanup", ConditionCompleted)),
+			),
+			wantOutput: "cleanup | cleaning\ncleanup | exited with exit status 3\napi | started\n",
+			wantError:  nil,
+		},
 		{
 			name: "a command exits with non-zero code",
 			group: NewGroup(
@@ -125,8 +178,9 @@ func TestGroup_RunContext(t *testing.T) {
 				tt.ctx = context.Background()
 			}
 
-			// modify internal stdouts for testability
-			var sb strings.Builder
+			// modify internal stdouts for testability, commands write to it
+			// concurrently
+			var sb syncBuffer
 			for i := range tt.group.commands {
 				tt.group.commands[i].stdout = &sb
 			}
@@ -154,3 +208,217 @@ func TestGroup_RunContext(t *testing.T) {
 		})
 	}
 }
+
+func TestGroup_FailedDependency(t *testing.T) {
+	testShell := getInstalledShells(t)[0]
+
+	tests := []struct {
+		name       string
+		conditions []Condition // of the dependents of the failing command
+		wantError  error
+	}{
+		{
+			name:       "only completed conditions allow failures",
+			conditions: []Condition{ConditionCompleted, ConditionCompleted},
+			wantError:  nil,
+		},
+		{
+			name:       "completed and ready conditions fail the Group",
+			conditions: []Condition{ConditionCompleted, ConditionReady},
+			wantError:  errors.New("cleanup: exit status 3"),
+		},
+		{
+			name:       "completed and completed-successfully conditions fail the Group",
+			conditions: []Condition{ConditionCompletedSuccessfully, ConditionCompleted},
+			wantError:  errors.New("cleanup: exit status 3"),
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			cleanup, err := NewShellCmd(testShell, "exit 3", Name("cleanup"))
+			if err != nil {
+				t.Fatalf("malformed test, failed NewShellCmd, err: %s", err)
+			}
+			group := NewGroup(cleanup)
+			for i, condition := range tt.conditions {
+				dependent, err := NewShellCmd(testShell, "true",
+					Name(fmt.Sprintf("dependent%d", i)), DependsOnCondition("cleanup", condition))
+				if err != nil {
+					t.Fatalf("malformed test, failed NewShellCmd, err: %s", err)
+				}
+				group.AddCommands(dependent)
+			}
+			var out syncBuffer
+			for _, cmd := range group.commands {
+				cmd.stdout = &out
+			}
+
+			ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+			defer cancel()
+			err = group.RunContext(ctx)
+
+			want, got := "nil", "nil"
+			if tt.wantError != nil {
+				want = tt.wantError.Error()
+			}
+			if err != nil {
+				got = err.Error()
+			}
+			if want != got {
+				t.Errorf("want err %q, got %q", want, got)
+			}
+		})
+	}
+}
+
+// syncBuffer is a strings.Builder that is safe for concurrent writes
+type syncBuffer struct {
+	mut sync.Mutex
+	sb  strings.Builder
+}
+
+func (b *syncBuffer) Write(p []byte) (int, error) {
+	b.mut.Lock()
+	defer b.mut.Unlock()
+	return b.sb.Write(p)
+}
+
+func (b *syncBuffer) String() string {
+	b.mut.Lock()
+	defer b.mut.Unlock()
+	return b.sb.String()
+}
+
+func TestGroup_ReplaceAndRemove(t *testing.T) {
+	testShell := getInstalledShells(t)[0]
+
+	var out syncBuffer
+	mustNewShellCmd := func(command string, opts ...ShellCmdOption) *ShellCmd {
+		cmd, err := NewShellCmd(testShell, command, opts...)
+		if err != nil {
+			t.Fatalf("malformed test, failed mustNewShellCmd(%s, opts...), err: %s", command, err)
+		}
+		cmd.stdout = &out
+		return cmd
+	}
+
+	group := NewGroup(
+		mustNewShellCmd("echo v1 && sleep 10", Name("server"), ReadyPattern("v1")),
+		mustNewShellCmd("sleep 10", Name("worker")),
+	)
+	errs := make(chan error, 1)
+	go func() {
+		errs <- group.RunContext(context.Background())
+	}()
+
+	waitForOutput := func(want string) {
+		deadline := time.Now().Add(5 * time.Second)
+		for !strings.Contains(out.String(), want) {
+			if time.Now().After(deadline) {
+				t.Fatalf("want output to contain %q, got %q", want, out.String())
+			}
+			time.Sleep(50 * time.Millisecond)
+		}
+	}
+	waitForOutput("server | v1\n")
+
+	// replaced and removed commands are interrupted, which is not an error
+	if err := group.Replace(mustNewShellCmd("echo v2", Name("server"))); err != nil {
+		t.Fatalf("want nil error replacing server, got %s", err)
+	}
+	waitForOutput("server | v2\n")
+	if err := group.Replace(mustNewShellCmd("echo added", Name("client"), DependsOn("server"))); err != nil {
+		t.Fatalf("want nil error adding client, got %s", err)
+	}
+	waitForOutput("client | added\n")
+	if err := group.Remove("worker"); err != nil {
+		t.Fatalf("want nil error removing worker, got %s", err)
+	}
+	if err := group.Remove("potato"); err == nil {
+		t.Errorf("want error removing a command that does not exist")
+	}
+
+	select {
+	case err := <-errs:
+		if err != nil {
+			t.Errorf("want nil error from group, got %s", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatalf("want group to finish once all commands exited")
+	}
+
+	if err := group.Replace(mustNewShellCmd("echo late", Name("server"))); err == nil {
+		t.Errorf("want error replacing a command of a finished group")
+	}
+}
+
+func TestGroup_Restart(t *testing.T) {
+	testShell := getInstalledShells(t)[0]
+
+	var out syncBuffer
+	mustNewShellCmd := func(command string, opts ...ShellCmdOption) *ShellCmd {
+		cmd, err := NewShellCmd(testShell, command, opts...)
+		if err != nil {
+			t.Fatalf("malformed test, failed mustNewShellCmd(%s, opts...), err: %s", command, err)
+		}
+		cmd.stdout = &out
+		return cmd
+	}
+
+	group := NewGroup(
+		mustNewShellCmd("echo db && sleep 10", Name("db"), ReadyPattern("db")),
+		mustNewShellCmd("echo api && sleep 10", Name("api"), DependsOn("db"), ReadyPattern("api")),
+		mustNewShellCmd("echo ui && sleep 10", Name("ui"), DependsOn("api")),
+		mustNewShellCmd("echo worker && sleep 10", Name("worker")),
+	)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	errs := make(chan error, 1)
+	go func() {
+		errs <- group.RunContext(ctx)
+	}()
+
+	waitForCount := func(want string, count int) {
+		deadline := time.Now().Add(5 * time.Second)
+		for strings.Count(out.String(), want) < count {
+			if time.Now().After(deadline) {
+				t.Fatalf("want output to contain %q %d times, got %q", want, count, out.String())
+			}
+			time.Sleep(50 * time.Millisecond)
+		}
+	}
+	waitForCount("ui | ui\n", 1)
+	waitForCount("worker | worker\n", 1)
+
+	if err := group.Restart("api", false); err != nil {
+		t.Fatalf("want nil error restarting api, got %s", err)
+	}
+	waitForCount("api | api\n", 2)
+
+	if err := group.Restart("db", true); err != nil {
+		t.Fatalf("want nil error restarting db, got %s", err)
+	}
+	waitForCount("db | db\n", 2)
+	waitForCount("api | api\n", 3)
+	waitForCount("ui | ui\n", 2)
+
+	if err := group.Restart("potato", false); err == nil {
+		t.Errorf("want error restarting a command that does not exist")
+	}
+
+	cancel()
+	select {
+	case <-errs:
+	case <-time.After(5 * time.Second):
+		t.Fatalf("want group to finish once its context is cancelled")
+	}
+	if got := strings.Count(out.String(), "worker | worker\n"); got != 1 {
+		t.Errorf("want worker to not be restarted, got output %q", out.String())
+	}
+	if got := strings.Count(out.String(), "ui | ui\n"); got != 2 {
+		t.Errorf("want ui to be restarted once, got output %q", out.String())
+	}
+}
@@ -0,0 +1,97 @@
+package cmdsync
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"syscall"
+)
+
+// BeforeHooks is a functional option that sets commands that run one after
+// another in the same shell, directory and environment before the ShellCmd
+// starts in a Group. The ShellCmd does not start if a hook fails, e.g.
+//
+//	cmdsync.BeforeHooks("mkdir -p tmp/uploads")
+func BeforeHooks(commands ...string) ShellCmdOption {
+	return func(s *ShellCmd) error {
+		s.beforeHooks = append(s.beforeHooks, commands...)
+		return nil
+	}
+}
+
+// AfterHooks is a functional option that sets commands that run one after
+// another once the ShellCmd has exited in a Group, including when the Group is
+// shutting down. Failing hooks are reported but do not stop the other hooks.
+func AfterHooks(commands ...string) ShellCmdOption {
+	return func(s *ShellCmd) error {
+		s.afterHooks = append(s.afterHooks, commands...)
+		return nil
+	}
+}
+
+// hook makes a ShellCmd that runs command like s would, its output is prefixed
+// with s's name and the kind of hook, e.g. "api before"
+func (s *ShellCmd) hook(kind, command string) *ShellCmd {
+	execCmd := s.shellCmd(context.Background(), command)
+	// hooks get their own process group too, so a second ctrl + c from the
+	// terminal does not reach after hooks that are cleaning up
+	execCmd.SysProcAttr = &syscall.SysProcAttr{Setpgid: true}
+
+	name := kind
+	if s.name != "" {
+		name = s.name + " " + kind
+	}
+	h := &ShellCmd{
+		command:   execCmd,
+		shell:     s.shell,
+		shellArgs: s.shellArgs,
+		name:      name,
+		color:     s.color,
+		stdout:    s.stdout,
+		exited:    make(chan struct{}),
+		stopping:  make(chan struct{}),
+		// keeps the export commands of s, see script
+		environment: s.environment,
+		// the output and exits of hooks do not count towards s's
+		stats: &commandStats{},
+	}
+	execCmd.Stdout = h
+	execCmd.Stderr = h
+	return h
+}
+
+// hooks makes a ShellCmd for each command
+func (s *ShellCmd) hooks(kind string, commands []string) []*ShellCmd {
+	hooks := make([]*ShellCmd, 0, len(commands))
+	for _, command := range commands {
+		hooks = append(hooks, s.hook(kind, command))
+	}
+	return hooks
+}
+
+// script returns the command string that a ShellCmd runs in its shell, without
+// the export commands of its environment
+func (s *ShellCmd) script() string {
+	return strings.TrimPrefix(s.command.Args[len(s.command.Args)-1], s.exportPrefix())
+}
+
+// runBeforeHooks runs hooks one after another until one fails
+func runBeforeHooks(ctx context.Context, hooks []*ShellCmd) error {
+	for _, hook := range hooks {
+		if err := hook.RunContext(ctx); err != nil {
+			return fmt.Errorf("before hook %q: %w", hook.script(), err)
+		}
+	}
+	return nil
+}
+
+// runAfterHooks runs all hooks one after another, they are not cancelled so
+// cleanup can finish while a Group shuts down. Failures are printed with the
+// hook's prefix.
+func runAfterHooks(hooks []*ShellCmd) {
+	for _, hook := range hooks {
+		if err := hook.Run(); err != nil {
+			hook.printStatus(fmt.Sprintf("after hook %q failed: %v", hook.script(), err))
+		}
+	}
+}
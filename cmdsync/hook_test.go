@@ -0,0 +1,200 @@
+package cmdsync
+
+import (
+	"context"
+	"os"
+	"strings"
+	"syscall"
+	"testing"
+	"time"
+)
+
+func TestGroup_Hooks(t *testing.T) {
+	testShell := getInstalledShells(t)[0]
+
+	var out syncBuffer
+	mustNewShellCmd := func(command string, opts ...ShellCmdOption) *ShellCmd {
+		cmd, err := NewShellCmd(testShell, command, opts...)
+		if err != nil {
+			t.Fatalf("malformed test, failed mustNewShellCmd(%s, opts...), err: %s", command, err)
+		}
+		cmd.stdout = &out
+		return cmd
+	}
+
+	group := NewGroup(
+		mustNewShellCmd("echo api && sleep 10", Name("api"),
+			// hooks get the environment, but report their own command
+			Environment(map[string]string{"TEST_HOOK_VAR": "api"}),
+			BeforeHooks("echo $TEST_HOOK_VAR setup"),
+			AfterHooks("exit 1", "echo $TEST_HOOK_VAR cleanup"),
+		),
+	)
+	err := group.AddHooks(
+		[]*ShellCmd{mustNewShellCmd("echo setup", Name("before"))},
+		[]*ShellCmd{mustNewShellCmd("sleep 0.2 && echo cleanup", Name("after"))},
+	)
+	if err != nil {
+		t.Fatalf("want nil error adding hooks, got %s", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	errs := make(chan error, 1)
+	go func() {
+		errs <- group.RunContext(ctx)
+	}()
+
+	deadline := time.Now().Add(5 * time.Second)
+	for !strings.Contains(out.String(), "api | api\n") {
+		if time.Now().After(deadline) {
+			t.Fatalf("want api to start, got output %q", out.String())
+		}
+		time.Sleep(50 * time.Millisecond)
+	}
+	// after hooks run even though the group is interrupted
+	cancel()
+	select {
+	case <-errs:
+	case <-time.After(5 * time.Second):
+		t.Fatalf("want group to finish once its context is cancelled")
+	}
+
+	wantInOrder := []string{
+		"before | setup\n",
+		"api before | api setup\n",
+		"api | api\n",
+		"api after | after hook \"exit 1\" failed: exit status 1\n",
+		"api after | api cleanup\n",
+		"after | cleanup\n",
+	}
+	got := out.String()
+	prev := -1
+	for _, want := range wantInOrder {
+		i := strings.Index(got, want)
+		if i <= prev {
+			t.Fatalf("want output to contain %q in order %q, got %q", want, wantInOrder, got)
+		}
+		prev = i
+	}
+}
+
+func TestGroup_FailingBeforeHooks(t *testing.T) {
+	testShell := getInstalledShells(t)[0]
+
+	tests := []struct {
+		name        string
+		groupBefore string
+		cmdBefore   string
+		wantErrPart string
+		wantOutput  []string
+		wantMissing string
+	}{
+		{
+			name:        "group before hook",
+			groupBefore: "exit 3",
+			cmdBefore:   "true",
+			wantErrPart: `before hook "exit 3": exit status 3`,
+			wantOutput:  []string{"after | cleanup\n"},
+			wantMissing: "api | api",
+		},
+		{
+			name:        "command before hook",
+			groupBefore: "true",
+			cmdBefore:   "exit 4",
+			wantErrPart: `api: before hook "exit 4": exit status 4`,
+			wantOutput:  []string{"api after | api cleanup\n", "after | cleanup\n"},
+			wantMissing: "api | api",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var out syncBuffer
+			mustNewShellCmd := func(command string, opts ...ShellCmdOption) *ShellCmd {
+				cmd, err := NewShellCmd(testShell, command, opts...)
+				if err != nil {
+					t.Fatalf("malformed test, failed mustNewShellCmd(%s, opts...), err: %s", command, err)
+				}
+				cmd.stdout = &out
+				return cmd
+			}
+
+			group := NewGroup(mustNewShellCmd("echo api", Name("api"),
+				BeforeHooks(tt.cmdBefore),
+				AfterHooks("echo api cleanup"),
+			))
+			group.AddHooks(
+				[]*ShellCmd{mustNewShellCmd(tt.groupBefore, Name("before"))},
+				[]*ShellCmd{mustNewShellCmd("echo cleanup", Name("after"))},
+			)
+
+			err := group.RunContext(context.Background())
+			if err == nil || !strings.Contains(err.Error(), tt.wantErrPart) {
+				t.Errorf("want error containing %q, got %v", tt.wantErrPart, err)
+			}
+			for _, want := range tt.wantOutput {
+				if !strings.Contains(out.String(), want) {
+					t.Errorf("want output to contain %q, got %q", want, out.String())
+				}
+			}
+			if strings.Contains(out.String(), tt.wantMissing) {
+				t.Errorf("want output to not contain %q, got %q", tt.wantMissing, out.String())
+			}
+		})
+	}
+}
+
+func TestGroup_InterruptedBeforeHook(t *testing.T) {
+	testShell := getInstalledShells(t)[0]
+
+	var out syncBuffer
+	mustNewShellCmd := func(command string, opts ...ShellCmdOption) *ShellCmd {
+		cmd, err := NewShellCmd(testShell, command, opts...)
+		if err != nil {
+			t.Fatalf("malformed test, failed mustNewShellCmd(%s, opts...), err: %s", command, err)
+		}
+		cmd.stdout = &out
+		return cmd
+	}
+
+	group := NewGroup(mustNewShellCmd("echo api", Name("api")))
+	group.AddHooks(
+		[]*ShellCmd{mustNewShellCmd("echo setup && sleep 10", Name("before"))},
+		[]*ShellCmd{mustNewShellCmd("echo cleanup && sleep 0.5 && echo cleaned up", Name("after"))},
+	)
+
+	errs := make(chan error, 1)
+	go func() {
+		errs <- group.Run()
+	}()
+
+	waitForOutput := func(want string) {
+		deadline := time.Now().Add(5 * time.Second)
+		for !strings.Contains(out.String(), want) {
+			if time.Now().After(deadline) {
+				t.Fatalf("want output to contain %q, got %q", want, out.String())
+			}
+			time.Sleep(20 * time.Millisecond)
+		}
+	}
+
+	// ctrl + c interrupts the before hook, a second one must not kill the test
+	// process while the after hook cleans up
+	waitForOutput("before | setup\n")
+	syscall.Kill(os.Getpid(), syscall.SIGINT)
+	waitForOutput("after | cleanup\n")
+	syscall.Kill(os.Getpid(), syscall.SIGINT)
+
+	select {
+	case err := <-errs:
+		if err == nil {
+			t.Errorf("want error from the interrupted before hook, got nil")
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatalf("want group to finish after its after hooks")
+	}
+	if !strings.Contains(out.String(), "after | cleaned up\n") || strings.Contains(out.String(), "api | api") {
+		t.Errorf("want after hook to finish without api starting, got %q", out.String())
+	}
+}
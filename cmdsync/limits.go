@@ -0,0 +1,133 @@
+package cmdsync
+
+import (
+	"fmt"
+	"math"
+	"os"
+	"os/exec"
+	"strings"
+	"time"
+)
+
+// Limits restrict the resources that a ShellCmd's processes can use, zero
+// values are not limited. Nice, NoFile, AddressSpace and CPUTime are inherited
+// by every process the command starts, AddressSpace and CPUTime apply to each
+// process on its own. Memory and CPUs cap all of the command's processes
+// together via a cgroup v2, where one can be created.
+type Limits struct {
+	Nice         int           // niceness from -20 (highest priority) to 19
+	NoFile       uint64        // max open files, RLIMIT_NOFILE
+	AddressSpace uint64        // max virtual memory in bytes, RLIMIT_AS
+	CPUTime      time.Duration // max CPU time, RLIMIT_CPU
+	Memory       uint64        // max memory in bytes, the cgroup's memory.max
+	CPUs         float64       // max CPUs, e.g. 0.5, the cgroup's cpu.max
+}
+
+// ResourceLimits is a functional option that limits the resources of the
+// command's processes, e.g. to keep a runaway dev server from using all memory
+//
+//	cmdsync.ResourceLimits(cmdsync.Limits{Nice: 10, Memory: 2 << 30})
+//
+// The limits apply before the command execs. Nice, NoFile, AddressSpace and
+// CPUTime are set by /bin/sh with ulimit and nice, which then execs the
+// command with its arguments unchanged, so every process it starts has them
+// too. Without /bin/sh or nice, they are set right after the process starts
+// instead, on Linux with prlimit(2). The process starts in its cgroup, which
+// needs Linux 5.7 or newer, or is moved into it right after starting when built
+// with Go older than 1.20. A limit that cannot be applied, e.g. without
+// permission to create a cgroup, is reported in the command's output and the
+// command runs without it.
+func ResourceLimits(limits Limits) ShellCmdOption {
+	return func(s *ShellCmd) error {
+		if limits.Nice < -20 || limits.Nice > 19 {
+			return fmt.Errorf("nice %d is not between -20 and 19", limits.Nice)
+		}
+		if limits.CPUTime < 0 || limits.CPUs < 0 {
+			return fmt.Errorf("resource limits cannot be negative")
+		}
+		s.limits = &limits
+		return nil
+	}
+}
+
+// shPath is the POSIX sh that sets limits before a command execs
+const shPath = "/bin/sh"
+
+// limitCommand makes cmd run via shPath, which sets limits' rlimits with ulimit
+// and then execs the command with its path and arguments unchanged, through
+// nice for limits' niceness, so the command has them from its first
+// instruction. It returns the limits that cannot be set this way, e.g. without
+// shPath or nice, which limitProcess sets once the process has started instead.
+func limitCommand(cmd *exec.Cmd, limits Limits) (late Limits) {
+	// a command that was not found fails to start as it would without limits
+	if cmd.Err != nil {
+		return Limits{}
+	}
+	late = Limits{Nice: limits.Nice, NoFile: limits.NoFile, AddressSpace: limits.AddressSpace, CPUTime: limits.CPUTime}
+	if _, err := os.Stat(shPath); err != nil {
+		return late
+	}
+
+	var script []string
+	rlimits := []struct {
+		name  string
+		flag  string
+		value uint64
+	}{
+		{"nofile", "-n", limits.NoFile},
+		// in KiB, rounded up
+		{"address-space", "-v", (limits.AddressSpace + 1023) / 1024},
+		{"cpu-time", "-t", uint64(math.Ceil(limits.CPUTime.Seconds()))},
+	}
+	for _, r := range rlimits {
+		if r.value > 0 {
+			// the command runs without the limits that fail to be set
+			script = append(script, fmt.Sprintf(`ulimit %s %d || echo "resource limits: %s is not limited" >&2`, r.flag, r.value, r.name))
+		}
+	}
+	late.NoFile, late.AddressSpace, late.CPUTime = 0, 0, 0
+
+	plainExec := `exec "$@"`
+	run := plainExec
+	// nice adjusts the niceness that the command would otherwise inherit
+	if increment := limits.Nice - ownNice(); limits.Nice == 0 || increment == 0 {
+		late.Nice = 0
+	} else if _, err := exec.LookPath("nice"); err == nil {
+		run = fmt.Sprintf(`exec nice -n %d "$@"`, increment)
+		late.Nice = 0
+	}
+	if len(script) == 0 && run == plainExec {
+		return late
+	}
+	script = append(script, run)
+
+	cmd.Args = append([]string{"sh", "-c", strings.Join(script, "; "), "sh", cmd.Path}, cmd.Args[1:]...)
+	cmd.Path = shPath
+	return late
+}
+
+// applyLimits prepares s's command to start in a cgroup with its memory and
+// cpu limits. The returned started func sets the limits that limitCommand could
+// not set once the process has started, cleanup removes the cgroup once the
+// process has exited, or if it failed to start.
+func (s *ShellCmd) applyLimits() (started, cleanup func()) {
+	placed, cleanup := func(int) error { return nil }, func() {}
+	if s.limits.Memory > 0 || s.limits.CPUs > 0 {
+		p, c, err := startInCgroup(s.command, *s.limits)
+		if err != nil {
+			s.printStatus(fmt.Sprintf("resource limits: memory and cpus are not limited: %v", err))
+		} else {
+			placed, cleanup = p, c
+		}
+	}
+	started = func() {
+		pid := s.command.Process.Pid
+		if err := placed(pid); err != nil {
+			s.printStatus(fmt.Sprintf("resource limits: memory and cpus are not limited: %v", err))
+		}
+		for _, err := range limitProcess(pid, s.lateLimits) {
+			s.printStatus(fmt.Sprintf("resource limits: %v", err))
+		}
+	}
+	return started, cleanup
+}
@@ -0,0 +1,163 @@
+//go:build linux
+// +build linux
+
+package cmdsync
+
+import (
+	"bufio"
+	"fmt"
+	"math"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"syscall"
+	"unsafe"
+)
+
+// cgroupRoots are where the cgroup v2 hierarchy is mounted, on its own or
+// next to the v1 hierarchies of a hybrid setup
+var cgroupRoots = []string{"/sys/fs/cgroup", "/sys/fs/cgroup/unified"}
+
+// cpuPeriod is the period of cpu.max in microseconds, the kernel's default
+const cpuPeriod = 100000
+
+// cgroups counts the cgroups made by this process, to name them
+var cgroups uint64
+
+// ownNice returns the niceness of oneterminal's process
+func ownNice() int {
+	// the raw syscall returns 20 - nice to avoid negative values
+	prio, err := syscall.Getpriority(syscall.PRIO_PROCESS, 0)
+	if err != nil {
+		return 0
+	}
+	return 20 - prio
+}
+
+// limitProcess sets the niceness of the process group led by pid and the
+// rlimits of its process. It returns an error for each limit that failed.
+func limitProcess(pid int, limits Limits) []error {
+	var errs []error
+	if limits.Nice != 0 {
+		// the command's process leads its own process group, see NewShellCmd
+		if err := syscall.Setpriority(syscall.PRIO_PGRP, pid, limits.Nice); err != nil {
+			errs = append(errs, fmt.Errorf("setting nice %d: %w", limits.Nice, err))
+		}
+	}
+	rlimits := []struct {
+		name     string
+		resource int
+		value    uint64
+	}{
+		{"nofile", syscall.RLIMIT_NOFILE, limits.NoFile},
+		{"address-space", syscall.RLIMIT_AS, limits.AddressSpace},
+		// in seconds, rounded up
+		{"cpu-time", syscall.RLIMIT_CPU, uint64(math.Ceil(limits.CPUTime.Seconds()))},
+	}
+	for _, r := range rlimits {
+		if r.value == 0 {
+			continue
+		}
+		if err := prlimit(pid, r.resource, r.value); err != nil {
+			errs = append(errs, fmt.Errorf("setting %s %d: %w", r.name, r.value, err))
+		}
+	}
+	return errs
+}
+
+// prlimit sets the soft and hard limit of a resource of the process pid
+func prlimit(pid, resource int, value uint64) error {
+	limit := syscall.Rlimit{Cur: value, Max: value}
+	_, _, errno := syscall.RawSyscall6(syscall.SYS_PRLIMIT64, uintptr(pid), uintptr(resource),
+		uintptr(unsafe.Pointer(&limit)), 0, 0, 0)
+	if errno != 0 {
+		return errno
+	}
+	return nil
+}
+
+// startInCgroup makes a new cgroup with the memory and cpu limits next to
+// oneterminal's own, and prepares cmd to start in it. The returned placed func
+// is called with the started process's pid, cleanup removes the cgroup.
+func startInCgroup(cmd *exec.Cmd, limits Limits) (placed func(pid int) error, cleanup func(), err error) {
+	dir, err := makeCgroup(limits)
+	if err != nil {
+		return nil, nil, err
+	}
+	placed, closeCgroup, err := placeInCgroup(cmd, dir)
+	if err != nil {
+		os.Remove(dir)
+		return nil, nil, err
+	}
+	return placed, func() {
+		closeCgroup()
+		// fails if processes that were started in the background are still
+		// running, which leaves an empty cgroup behind
+		os.Remove(dir)
+	}, nil
+}
+
+// makeCgroup makes a new cgroup next to oneterminal's own with the memory and
+// cpu limits set. It returns the directory of the cgroup.
+func makeCgroup(limits Limits) (string, error) {
+	var root string
+	for _, dir := range cgroupRoots {
+		if _, err := os.Stat(filepath.Join(dir, "cgroup.controllers")); err == nil {
+			root = dir
+			break
+		}
+	}
+	if root == "" {
+		return "", fmt.Errorf("no cgroup v2 hierarchy at %s", strings.Join(cgroupRoots, " or "))
+	}
+	own, err := ownCgroup()
+	if err != nil {
+		return "", err
+	}
+	// cgroups with processes cannot have children that limit resources
+	n := atomic.AddUint64(&cgroups, 1)
+	dir := filepath.Join(root, filepath.Dir(own), fmt.Sprintf("oneterminal-%d-%d", os.Getpid(), n))
+	if err := os.Mkdir(dir, 0755); err != nil {
+		return "", err
+	}
+
+	var files [][2]string
+	if limits.Memory > 0 {
+		files = append(files, [2]string{"memory.max", strconv.FormatUint(limits.Memory, 10)})
+	}
+	if limits.CPUs > 0 {
+		quota := int(math.Round(limits.CPUs * cpuPeriod))
+		files = append(files, [2]string{"cpu.max", fmt.Sprintf("%d %d", quota, cpuPeriod)})
+	}
+	for _, f := range files {
+		if _, err := os.Stat(filepath.Join(dir, f[0])); err != nil {
+			os.Remove(dir)
+			return "", fmt.Errorf("the %s controller is not enabled in %s", strings.Split(f[0], ".")[0], filepath.Dir(dir))
+		}
+		if err := os.WriteFile(filepath.Join(dir, f[0]), []byte(f[1]), 0644); err != nil {
+			os.Remove(dir)
+			return "", fmt.Errorf("writing %s: %w", f[0], err)
+		}
+	}
+	return dir, nil
+}
+
+// ownCgroup returns the cgroup v2 path of oneterminal's process, e.g.
+// /user.slice/user-1000.slice/session-2.scope
+func ownCgroup() (string, error) {
+	f, err := os.Open("/proc/self/cgroup")
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		if path := strings.TrimPrefix(scanner.Text(), "0::"); path != scanner.Text() {
+			return path, nil
+		}
+	}
+	return "", fmt.Errorf("no cgroup v2 entry in /proc/self/cgroup")
+}
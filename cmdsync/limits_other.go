@@ -0,0 +1,38 @@
+//go:build !linux
+// +build !linux
+
+package cmdsync
+
+import (
+	"fmt"
+	"os/exec"
+	"syscall"
+)
+
+// ownNice returns the niceness of oneterminal's process
+func ownNice() int {
+	prio, err := syscall.Getpriority(syscall.PRIO_PROCESS, 0)
+	if err != nil {
+		return 0
+	}
+	return prio
+}
+
+// limitProcess sets the niceness of the process group led by pid, the rlimits
+// of another process can only be set on Linux
+func limitProcess(pid int, limits Limits) []error {
+	var errs []error
+	if limits.Nice != 0 {
+		if err := syscall.Setpriority(syscall.PRIO_PGRP, pid, limits.Nice); err != nil {
+			errs = append(errs, fmt.Errorf("setting nice %d: %w", limits.Nice, err))
+		}
+	}
+	if limits.NoFile > 0 || limits.AddressSpace > 0 || limits.CPUTime > 0 {
+		errs = append(errs, fmt.Errorf("nofile, address-space and cpu-time need Linux"))
+	}
+	return errs
+}
+
+func startInCgroup(cmd *exec.Cmd, limits Limits) (func(int) error, func(), error) {
+	return nil, nil, fmt.Errorf("cgroups need Linux")
+}
@@ -0,0 +1,112 @@
+package cmdsync
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestShellCmd_ResourceLimits(t *testing.T) {
+	testShell := getInstalledShells(t)[0]
+
+	tests := []struct {
+		name        string
+		command     string
+		argv        []string
+		limits      Limits
+		wantOutput  string
+		wantErrPart string
+	}{
+		{
+			name:       "nice",
+			command:    "nice",
+			limits:     Limits{Nice: 7},
+			wantOutput: "7\n",
+		},
+		{
+			name:       "open files",
+			command:    "ulimit -n",
+			limits:     Limits{NoFile: 123},
+			wantOutput: "123\n",
+		},
+		{
+			name:       "cpu time rounds up to seconds",
+			command:    "ulimit -t",
+			limits:     Limits{CPUTime: 1500 * time.Millisecond},
+			wantOutput: "2\n",
+		},
+		{
+			name:       "address space rounds up to KiB",
+			command:    "ulimit -v",
+			limits:     Limits{AddressSpace: 1<<30 + 1},
+			wantOutput: "1048577\n",
+		},
+		{
+			name:       "child forked right away",
+			command:    `sh -c "nice; ulimit -n"`,
+			limits:     Limits{Nice: 4, NoFile: 77},
+			wantOutput: "4\n77\n",
+		},
+		{
+			name:       "argv command",
+			argv:       []string{"sh", "-c", "nice; ulimit -n"},
+			limits:     Limits{Nice: 3, NoFile: 99},
+			wantOutput: "3\n99\n",
+		},
+		{
+			name:        "nice out of range",
+			limits:      Limits{Nice: 20},
+			wantErrPart: "nice 20 is not between -20 and 19",
+		},
+		{
+			name:        "negative cpus",
+			limits:      Limits{CPUs: -1},
+			wantErrPart: "resource limits cannot be negative",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			opts := []ShellCmdOption{ResourceLimits(tt.limits)}
+			if tt.argv != nil {
+				opts = append(opts, Argv(tt.argv...))
+			}
+			cmd, err := NewShellCmd(testShell, tt.command, opts...)
+			if tt.wantErrPart != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErrPart) {
+					t.Fatalf("want error containing %q, got %v", tt.wantErrPart, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("want nil error, got %s", err)
+			}
+			var out syncBuffer
+			cmd.stdout = &out
+
+			if err := cmd.Run(); err != nil {
+				t.Fatalf("want nil error running, got %s", err)
+			}
+			if got := out.String(); got != tt.wantOutput {
+				t.Errorf("want output %q, got %q", tt.wantOutput, got)
+			}
+		})
+	}
+}
+
+func TestShellCmd_ResourceLimitsKeepArgv(t *testing.T) {
+	argv := []string{"printf", "%s|", "$HOME", "*", "it's"}
+	cmd, err := NewShellCmd("", "", Argv(argv...), ResourceLimits(Limits{Nice: 5, NoFile: 64}))
+	if err != nil {
+		t.Fatalf("malformed test, failed NewShellCmd, err: %s", err)
+	}
+	var out syncBuffer
+	cmd.stdout = &out
+
+	if err := cmd.Run(); err != nil {
+		t.Fatalf("want nil error running, got %s", err)
+	}
+	if want := "$HOME|*|it's|"; out.String() != want {
+		t.Errorf("want output %q, got %q", want, out.String())
+	}
+}
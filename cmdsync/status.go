@@ -0,0 +1,92 @@
+package cmdsync
+
+import (
+	"os"
+	"sync"
+	"syscall"
+)
+
+// commandStats are the counters of a command, which carry over when it is
+// restarted or replaced in a Group
+type commandStats struct {
+	mut      sync.Mutex
+	lines    uint64
+	restarts uint64
+	exits    map[int]uint64
+}
+
+func (c *commandStats) addLines(n int) {
+	c.mut.Lock()
+	defer c.mut.Unlock()
+	c.lines += uint64(n)
+}
+
+func (c *commandStats) addRestart() {
+	c.mut.Lock()
+	defer c.mut.Unlock()
+	c.restarts++
+}
+
+// addExit counts the exit of a process by its exit code, or 128 plus the
+// signal that killed it like shells report it
+func (c *commandStats) addExit(state *os.ProcessState) {
+	code := state.ExitCode()
+	if status, ok := state.Sys().(syscall.WaitStatus); ok && status.Signaled() {
+		code = 128 + int(status.Signal())
+	}
+	c.mut.Lock()
+	defer c.mut.Unlock()
+	if c.exits == nil {
+		c.exits = make(map[int]uint64)
+	}
+	c.exits[code]++
+}
+
+// CommandStatus is a snapshot of one of a Group's commands, see Group.Status
+type CommandStatus struct {
+	Name     string
+	Running  bool           // if its process has started and not exited yet
+	Ready    bool           // if its dependents can start
+	Restarts uint64         // times it was restarted or replaced in the Group
+	Exits    map[int]uint64 // number of exits by exit code, 128+n for signal n
+	Lines    uint64         // lines of output, including silenced output
+
+	// Usage of a running command's process group, if it can be sampled.
+	// CPUPercent is not set, as it depends on when Usage was last called.
+	Usage *Usage
+}
+
+// Status returns a snapshot of each of the Group's commands. Its counters
+// include the runs of the commands that they restarted or replaced.
+func (g *Group) Status() []CommandStatus {
+	g.mut.RLock()
+	commands := append([]*ShellCmd{}, g.commands...)
+	g.mut.RUnlock()
+
+	statuses := make([]CommandStatus, 0, len(commands))
+	for _, cmd := range commands {
+		status := CommandStatus{Name: cmd.name}
+
+		cmd.readyMut.RLock()
+		status.Running = cmd.started && !cmd.completed
+		status.Ready = cmd.ready
+		cmd.readyMut.RUnlock()
+
+		cmd.stats.mut.Lock()
+		status.Restarts = cmd.stats.restarts
+		status.Lines = cmd.stats.lines
+		status.Exits = make(map[int]uint64, len(cmd.stats.exits))
+		for code, n := range cmd.stats.exits {
+			status.Exits[code] = n
+		}
+		cmd.stats.mut.Unlock()
+
+		if status.Running {
+			if usage, err := sampleProcessGroup(cmd.command.Process.Pid); err == nil {
+				status.Usage = &usage
+			}
+		}
+		statuses = append(statuses, status)
+	}
+	return statuses
+}
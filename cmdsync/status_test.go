@@ -0,0 +1,71 @@
+package cmdsync
+
+import (
+	"context"
+	"reflect"
+	"runtime"
+	"testing"
+	"time"
+)
+
+func TestGroup_Status(t *testing.T) {
+	testShell := getInstalledShells(t)[0]
+
+	var out syncBuffer
+	api, err := NewShellCmd(testShell, "printf 'a\\nb\\n'; sleep 10", Name("api"))
+	if err != nil {
+		t.Fatalf("malformed test, failed NewShellCmd, err: %s", err)
+	}
+	api.stdout = &out
+	group := NewGroup(api)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	errs := make(chan error, 1)
+	go func() {
+		errs <- group.RunContext(ctx)
+	}()
+
+	waitForStatus := func(desc string, done func(CommandStatus) bool) CommandStatus {
+		deadline := time.Now().Add(5 * time.Second)
+		for {
+			status := group.Status()[0]
+			if done(status) {
+				return status
+			}
+			if time.Now().After(deadline) {
+				t.Fatalf("want %s, got status %+v", desc, status)
+			}
+			time.Sleep(50 * time.Millisecond)
+		}
+	}
+
+	status := waitForStatus("api to print 2 lines", func(s CommandStatus) bool { return s.Lines == 2 })
+	if !status.Running || status.Ready || status.Restarts != 0 || len(status.Exits) != 0 {
+		t.Errorf("want api running without restarts or exits, got %+v", status)
+	}
+	if runtime.GOOS == "linux" && (status.Usage == nil || status.Usage.RSS == 0) {
+		t.Errorf("want usage of running api, got %+v", status.Usage)
+	}
+
+	if err := group.Restart("api", false); err != nil {
+		t.Fatalf("want nil error restarting, got %s", err)
+	}
+	// the interrupted run counts as an exit by SIGINT
+	status = waitForStatus("api to print 2 more lines after restarting", func(s CommandStatus) bool { return s.Lines == 4 })
+	if !status.Running || status.Restarts != 1 || !reflect.DeepEqual(status.Exits, map[int]uint64{130: 1}) {
+		t.Errorf("want api running again after 1 restart and 1 exit with code 130, got %+v", status)
+	}
+
+	cancel()
+	select {
+	case <-errs:
+	case <-time.After(5 * time.Second):
+		t.Fatalf("want group to finish once its context is cancelled")
+	}
+	// the group returns before the interrupted process has been waited for
+	status = waitForStatus("api to exit again", func(s CommandStatus) bool { return s.Exits[130] == 2 })
+	if status.Running || status.Usage != nil {
+		t.Errorf("want api stopped without usage, got %+v", status)
+	}
+}
@@ -0,0 +1,86 @@
+package cmdsync
+
+import (
+	"fmt"
+	"time"
+)
+
+// Usage is the resource usage of all the processes in a ShellCmd's process
+// group, which includes any processes it started that did not leave it
+type Usage struct {
+	CPUPercent float64       // since the previous sample, 100 is one full CPU core
+	CPUTime    time.Duration // used by the processes and the children they waited for
+	RSS        uint64        // resident memory in bytes
+	Threads    int
+	Children   int // processes other than the command's own
+}
+
+// usageSample is the previous sample that CPUPercent is measured against
+type usageSample struct {
+	cpu time.Duration // CPU time used by the process group
+	at  time.Time
+}
+
+// Usage samples the resource usage of the command's process group. CPUPercent
+// is the CPU used since the previous call, or since the command started on the
+// first call. It errors if the command is not running, or if usage cannot be
+// sampled on this platform.
+func (s *ShellCmd) Usage() (Usage, error) {
+	s.readyMut.RLock()
+	running, startedAt := s.started && !s.completed, s.startedAt
+	s.readyMut.RUnlock()
+	if !running {
+		return Usage{}, fmt.Errorf("%s is not running", s.name)
+	}
+
+	s.usageMut.Lock()
+	defer s.usageMut.Unlock()
+	// the process leads its own process group, see NewShellCmd
+	usage, err := sampleProcessGroup(s.command.Process.Pid)
+	if err != nil {
+		return Usage{}, err
+	}
+	if s.lastUsage.at.IsZero() {
+		s.lastUsage.at = startedAt
+	}
+	now := time.Now()
+	usage.CPUPercent = s.lastUsage.cpuPercent(usage.CPUTime, now)
+	s.lastUsage = usageSample{cpu: usage.CPUTime, at: now}
+	return usage, nil
+}
+
+// cpuPercent is the CPU used between the sample and a later one with cpu time
+// used at now. It is 0 if the CPU time went down, e.g. when a process that used
+// some exited without being waited for.
+func (u usageSample) cpuPercent(cpu time.Duration, now time.Time) float64 {
+	elapsed := now.Sub(u.at)
+	if elapsed <= 0 || cpu < u.cpu {
+		return 0
+	}
+	return 100 * float64(cpu-u.cpu) / float64(elapsed)
+}
+
+// CommandUsage is the resource usage of one of a Group's commands
+type CommandUsage struct {
+	Name string
+	Usage
+}
+
+// Usage samples the resource usage of the Group's running commands, see
+// ShellCmd.Usage. Commands that are not running are left out, as are any whose
+// usage cannot be sampled.
+func (g *Group) Usage() []CommandUsage {
+	g.mut.RLock()
+	commands := append([]*ShellCmd{}, g.commands...)
+	g.mut.RUnlock()
+
+	var usages []CommandUsage
+	for _, cmd := range commands {
+		usage, err := cmd.Usage()
+		if err != nil {
+			continue
+		}
+		usages = append(usages, CommandUsage{Name: cmd.name, Usage: usage})
+	}
+	return usages
+}
@@ -0,0 +1,65 @@
+//go:build linux
+// +build linux
+
+package cmdsync
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// clockTicks is the USER_HZ that /proc reports CPU times in, which is 100 on
+// every architecture Go supports
+const clockTicks = 100
+
+// sampleProcessGroup sums the CPU time and usage of the processes in a process
+// group from /proc/<pid>/stat, see proc(5)
+func sampleProcessGroup(pgid int) (Usage, error) {
+	entries, err := os.ReadDir("/proc")
+	if err != nil {
+		return Usage{}, err
+	}
+
+	var ticks uint64
+	var usage Usage
+	processes := 0
+	for _, entry := range entries {
+		pid, err := strconv.Atoi(entry.Name())
+		if err != nil {
+			continue
+		}
+		contents, err := os.ReadFile(fmt.Sprintf("/proc/%d/stat", pid))
+		if err != nil {
+			// the process exited since reading /proc
+			continue
+		}
+		// the process name in parentheses can contain spaces, fields[0] is
+		// the state, the 3rd field in proc(5)
+		fields := strings.Fields(string(contents[bytes.LastIndexByte(contents, ')')+1:]))
+		if len(fields) < 22 || fields[2] != strconv.Itoa(pgid) || fields[0] == "Z" {
+			continue
+		}
+		processes++
+		// utime, stime, and cutime, cstime of children that were waited for
+		for _, field := range fields[11:15] {
+			n, _ := strconv.ParseInt(field, 10, 64)
+			if n > 0 {
+				ticks += uint64(n)
+			}
+		}
+		threads, _ := strconv.Atoi(fields[17])
+		usage.Threads += threads
+		pages, _ := strconv.ParseUint(fields[21], 10, 64)
+		usage.RSS += pages * uint64(os.Getpagesize())
+	}
+	if processes == 0 {
+		return Usage{}, fmt.Errorf("no processes in process group %d", pgid)
+	}
+	usage.Children = processes - 1
+	usage.CPUTime = time.Duration(ticks) * time.Second / clockTicks
+	return usage, nil
+}
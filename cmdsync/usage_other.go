@@ -0,0 +1,10 @@
+//go:build !linux
+// +build !linux
+
+package cmdsync
+
+import "fmt"
+
+func sampleProcessGroup(pgid int) (Usage, error) {
+	return Usage{}, fmt.Errorf("sampling usage is only supported on Linux")
+}
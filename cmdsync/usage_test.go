@@ -0,0 +1,115 @@
+package cmdsync
+
+import (
+	"context"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestShellCmd_Usage(t *testing.T) {
+	if runtime.GOOS != "linux" {
+		t.Skip("usage is only sampled on Linux")
+	}
+	testShell := getInstalledShells(t)[0]
+
+	cmd, err := NewShellCmd(testShell, "sleep 10 & (while :; do :; done) & wait", Name("busy"))
+	if err != nil {
+		t.Fatalf("malformed test, failed NewShellCmd, err: %s", err)
+	}
+	cmd.stdout = &syncBuffer{}
+
+	if _, err := cmd.Usage(); err == nil || !strings.Contains(err.Error(), "busy is not running") {
+		t.Errorf("want error that busy is not running, got %v", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	done := make(chan error, 1)
+	go func() {
+		done <- cmd.RunContext(ctx)
+	}()
+
+	deadline := time.Now().Add(5 * time.Second)
+	var usage Usage
+	for {
+		time.Sleep(200 * time.Millisecond)
+		usage, err = cmd.Usage()
+		if err == nil && usage.Children >= 2 && usage.CPUPercent > 0 {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("want busy to start 2 child processes and use CPU, got usage %+v and error %v", usage, err)
+		}
+	}
+	if usage.RSS == 0 || usage.Threads < 3 {
+		t.Errorf("want memory and a thread per process, got %+v", usage)
+	}
+
+	cancel()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatalf("want busy to exit once interrupted")
+	}
+	if _, err := cmd.Usage(); err == nil {
+		t.Errorf("want error once busy has exited, got nil")
+	}
+}
+
+func TestUsageSample_CPUPercent(t *testing.T) {
+	at := time.Date(2021, 6, 1, 12, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name string
+		last usageSample
+		cpu  time.Duration
+		now  time.Time
+		want float64
+	}{
+		{
+			name: "idle",
+			last: usageSample{cpu: time.Second, at: at},
+			cpu:  time.Second,
+			now:  at.Add(2 * time.Second),
+			want: 0,
+		},
+		{
+			name: "half a core",
+			last: usageSample{cpu: time.Second, at: at},
+			cpu:  2 * time.Second,
+			now:  at.Add(2 * time.Second),
+			want: 50,
+		},
+		{
+			name: "multiple cores",
+			last: usageSample{at: at},
+			cpu:  3 * time.Second,
+			now:  at.Add(time.Second),
+			want: 300,
+		},
+		{
+			name: "cpu time went down",
+			last: usageSample{cpu: 2 * time.Second, at: at},
+			cpu:  time.Second,
+			now:  at.Add(time.Second),
+			want: 0,
+		},
+		{
+			name: "no time elapsed",
+			last: usageSample{at: at},
+			cpu:  time.Second,
+			now:  at,
+			want: 0,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.last.cpuPercent(tt.cpu, tt.now); got != tt.want {
+				t.Errorf("want %.1f%%, got %.1f%%", tt.want, got)
+			}
+		})
+	}
+}
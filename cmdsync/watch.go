@@ -0,0 +1,297 @@
+package cmdsync
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// WatchConfig configures which file changes restart a ShellCmd, see Watch
+type WatchConfig struct {
+	// Paths are files and directories to watch, directories are watched
+	// recursively. Relative paths are resolved against the command's directory,
+	// which is also watched if Paths is empty.
+	Paths []string
+	// Include are glob patterns that changed files must match, if set. Patterns
+	// without a "/" match any file or directory name in the path, others match
+	// the path relative to the watched directory. "**" matches any number of
+	// directories.
+	Include []string
+	// Exclude are glob patterns of files and directories to ignore, e.g.
+	// "*.log", "node_modules" or "dist/**". Excluded directories are not
+	// watched and changes inside .git directories are always ignored.
+	Exclude []string
+	// Debounce is how long to wait for changes to settle before restarting,
+	// defaults to DefaultDebounce
+	Debounce time.Duration
+	// RestartDependents also restarts the commands that depend on this one
+	RestartDependents bool
+}
+
+// DefaultDebounce is the Debounce of a WatchConfig that does not set one
+const DefaultDebounce = 300 * time.Millisecond
+
+// Watch is a functional option that restarts the ShellCmd when files matching
+// config change while it is running in a Group. A watched ShellCmd that exits,
+// successfully or not, waits for its files to change and is then restarted
+// instead of finishing.
+func Watch(config WatchConfig) ShellCmdOption {
+	return func(s *ShellCmd) error {
+		for _, patterns := range [][]string{config.Include, config.Exclude} {
+			if _, err := compileGlobs(patterns); err != nil {
+				return err
+			}
+		}
+		if config.Debounce <= 0 {
+			config.Debounce = DefaultDebounce
+		}
+		s.watch = &config
+		return nil
+	}
+}
+
+// waitForRestart blocks a watched ShellCmd that exited until a Group restarts
+// it or ctx is done. Failures are printed rather than returned, so a broken
+// build does not stop the rest of the Group while it is being fixed.
+func (s *ShellCmd) waitForRestart(ctx context.Context, err error) error {
+	if err != nil {
+		s.printStatus(fmt.Sprintf("exited with %v, restarting when files change", err))
+	} else {
+		s.printStatus("exited, restarting when files change")
+	}
+
+	select {
+	case <-ctx.Done():
+	case <-s.stopping:
+	}
+	return nil
+}
+
+// watchRoot is a file or directory that is being watched
+type watchRoot struct {
+	path  string
+	isDir bool
+}
+
+// fileWatcher reports the paths of files that change within its roots.
+// Directories are watched recursively, skipping those that skipDir returns true
+// for, and files are reported along with the other files of their directory.
+type fileWatcher interface {
+	Events() <-chan string
+	Close() error
+}
+
+// watchFilter decides which changed paths restart a ShellCmd
+type watchFilter struct {
+	roots    []watchRoot
+	includes []globPattern
+	excludes []globPattern
+}
+
+// globPattern is a compiled glob pattern. Patterns without a "/" are matched
+// against each element of a path, others against the whole relative path
+type globPattern struct {
+	r        *regexp.Regexp
+	nameOnly bool
+}
+
+func (p globPattern) match(rel string) bool {
+	if !p.nameOnly {
+		return p.r.MatchString(rel)
+	}
+	for _, name := range strings.Split(rel, "/") {
+		if p.r.MatchString(name) {
+			return true
+		}
+	}
+	return false
+}
+
+// watchChanges sends on the returned channel whenever files matching the
+// ShellCmd's WatchConfig change, at most once per debounce period. The
+// channel is closed once ctx is done.
+func (s *ShellCmd) watchChanges(ctx context.Context) (<-chan struct{}, error) {
+	filter, err := newWatchFilter(*s.watch, s.command.Dir)
+	if err != nil {
+		return nil, err
+	}
+	watcher, err := newFileWatcher(filter.roots, filter.skipDir)
+	if err != nil {
+		return nil, fmt.Errorf("watching files: %w", err)
+	}
+
+	changes := make(chan struct{})
+	go func() {
+		defer close(changes)
+		defer watcher.Close()
+
+		var debounce <-chan time.Time
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case path, ok := <-watcher.Events():
+				if !ok {
+					return
+				}
+				if filter.matches(path) {
+					debounce = time.After(s.watch.Debounce)
+				}
+			case <-debounce:
+				debounce = nil
+				select {
+				case changes <- struct{}{}:
+				case <-ctx.Done():
+					return
+				}
+			}
+		}
+	}()
+	return changes, nil
+}
+
+// newWatchFilter resolves the config's paths against dir and compiles its
+// patterns
+func newWatchFilter(config WatchConfig, dir string) (*watchFilter, error) {
+	paths := config.Paths
+	if len(paths) == 0 {
+		paths = []string{"."}
+	}
+
+	filter := &watchFilter{}
+	for _, p := range paths {
+		expanded := ExpandDir(p)
+		if !filepath.IsAbs(expanded) {
+			expanded = filepath.Join(dir, expanded)
+		}
+		abs, err := filepath.Abs(expanded)
+		if err != nil {
+			return nil, err
+		}
+		info, err := os.Stat(abs)
+		if err != nil {
+			return nil, fmt.Errorf("watch path %q does not exist", p)
+		}
+		filter.roots = append(filter.roots, watchRoot{path: abs, isDir: info.IsDir()})
+	}
+
+	var err error
+	if filter.includes, err = compileGlobs(config.Include); err != nil {
+		return nil, err
+	}
+	if filter.excludes, err = compileGlobs(config.Exclude); err != nil {
+		return nil, err
+	}
+	return filter, nil
+}
+
+// relative returns path relative to the watched directory that contains it
+func (f *watchFilter) relative(path string) (string, bool) {
+	for _, root := range f.roots {
+		if !root.isDir {
+			continue
+		}
+		rel, err := filepath.Rel(root.path, path)
+		if err == nil && rel != ".." && !strings.HasPrefix(rel, "../") {
+			return filepath.ToSlash(rel), true
+		}
+	}
+	return "", false
+}
+
+// matches returns true if a change to path should restart the ShellCmd.
+// Explicitly watched files always match, other files must be within a watched
+// directory and pass the include and exclude patterns.
+func (f *watchFilter) matches(path string) bool {
+	for _, root := range f.roots {
+		if !root.isDir && root.path == path {
+			return true
+		}
+	}
+	rel, ok := f.relative(path)
+	if !ok || f.ignored(rel) {
+		return false
+	}
+	if len(f.includes) == 0 {
+		return true
+	}
+	for _, p := range f.includes {
+		if p.match(rel) {
+			return true
+		}
+	}
+	return false
+}
+
+// skipDir returns true if nothing within the directory can match
+func (f *watchFilter) skipDir(path string) bool {
+	rel, ok := f.relative(path)
+	return ok && rel != "." && (f.ignored(rel) || f.ignored(rel+"/"))
+}
+
+// ignored returns true if rel is within a .git directory or excluded
+func (f *watchFilter) ignored(rel string) bool {
+	for _, name := range strings.Split(rel, "/") {
+		if name == ".git" {
+			return true
+		}
+	}
+	for _, p := range f.excludes {
+		if p.match(rel) {
+			return true
+		}
+	}
+	return false
+}
+
+func compileGlobs(patterns []string) ([]globPattern, error) {
+	var globs []globPattern
+	for _, pattern := range patterns {
+		r, err := globRegexp(pattern)
+		if err != nil {
+			return nil, fmt.Errorf("invalid watch pattern %q: %w", pattern, err)
+		}
+		globs = append(globs, globPattern{r: r, nameOnly: !strings.Contains(pattern, "/")})
+	}
+	return globs, nil
+}
+
+// globRegexp converts a glob pattern into a regexp. "**" matches across
+// directories while "*" and "?" do not, and a leading "**/" also matches no
+// directories at all
+func globRegexp(pattern string) (*regexp.Regexp, error) {
+	var sb strings.Builder
+	sb.WriteString("^")
+	for i := 0; i < len(pattern); i++ {
+		switch c := pattern[i]; c {
+		case '*':
+			switch {
+			case strings.HasPrefix(pattern[i:], "**/"):
+				sb.WriteString("(.*/)?")
+				i += 2
+			case strings.HasPrefix(pattern[i:], "**"):
+				sb.WriteString(".*")
+				i++
+			default:
+				sb.WriteString("[^/]*")
+			}
+		case '?':
+			sb.WriteString("[^/]")
+		case '[':
+			end := strings.IndexByte(pattern[i:], ']')
+			if end < 0 {
+				return nil, fmt.Errorf("missing closing ]")
+			}
+			sb.WriteString(pattern[i : i+end+1])
+			i += end
+		default:
+			sb.WriteString(regexp.QuoteMeta(string(c)))
+		}
+	}
+	sb.WriteString("$")
+	return regexp.Compile(sb.String())
+}
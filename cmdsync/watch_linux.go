@@ -0,0 +1,153 @@
+//go:build linux
+// +build linux
+
+package cmdsync
+
+import (
+	"bytes"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sync"
+	"syscall"
+	"unsafe"
+)
+
+// inotifyMask are the events that count as a file changing
+const inotifyMask = syscall.IN_CREATE | syscall.IN_CLOSE_WRITE | syscall.IN_MODIFY |
+	syscall.IN_ATTRIB | syscall.IN_DELETE | syscall.IN_MOVED_FROM | syscall.IN_MOVED_TO
+
+// inotifyWatcher is a fileWatcher that uses inotify. inotify is not recursive,
+// so every directory is watched separately and new directories are added as
+// they are created.
+type inotifyWatcher struct {
+	fd      int // File.Fd() would make reads blocking
+	file    *os.File
+	skipDir func(path string) bool
+	events  chan string
+	done    chan struct{} // closed by Close
+
+	mut       sync.Mutex
+	dirs      map[int32]string // watch descriptors to directories
+	recursive map[string]bool  // if new subdirectories of a directory are watched
+}
+
+func newFileWatcher(roots []watchRoot, skipDir func(path string) bool) (fileWatcher, error) {
+	fd, err := syscall.InotifyInit1(syscall.IN_CLOEXEC | syscall.IN_NONBLOCK)
+	if err != nil {
+		return nil, os.NewSyscallError("inotify_init1", err)
+	}
+	w := &inotifyWatcher{
+		// non-blocking file descriptors use the runtime's poller, so Close
+		// unblocks pending reads
+		fd:        fd,
+		file:      os.NewFile(uintptr(fd), "inotify"),
+		skipDir:   skipDir,
+		events:    make(chan string),
+		done:      make(chan struct{}),
+		dirs:      make(map[int32]string),
+		recursive: make(map[string]bool),
+	}
+
+	for _, root := range roots {
+		if root.isDir {
+			err = w.addTree(root.path)
+		} else {
+			// watching the file itself would miss editors that replace files
+			err = w.add(filepath.Dir(root.path), false)
+		}
+		if err != nil {
+			w.file.Close()
+			return nil, err
+		}
+	}
+
+	go w.read()
+	return w, nil
+}
+
+func (w *inotifyWatcher) Events() <-chan string {
+	return w.events
+}
+
+func (w *inotifyWatcher) Close() error {
+	close(w.done)
+	return w.file.Close()
+}
+
+// add watches a single directory
+func (w *inotifyWatcher) add(dir string, recursive bool) error {
+	wd, err := syscall.InotifyAddWatch(w.fd, dir, inotifyMask)
+	if err != nil {
+		return &os.PathError{Op: "inotify_add_watch", Path: dir, Err: err}
+	}
+	w.mut.Lock()
+	defer w.mut.Unlock()
+	w.dirs[int32(wd)] = dir
+	w.recursive[dir] = w.recursive[dir] || recursive
+	return nil
+}
+
+// addTree watches dir and all of its subdirectories
+func (w *inotifyWatcher) addTree(dir string) error {
+	return filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			// directories can be removed while walking
+			if path != dir && os.IsNotExist(err) {
+				return nil
+			}
+			return err
+		}
+		if !d.IsDir() {
+			return nil
+		}
+		if path != dir && w.skipDir(path) {
+			return filepath.SkipDir
+		}
+		return w.add(path, true)
+	})
+}
+
+// read sends the paths of inotify events until the watcher is closed
+func (w *inotifyWatcher) read() {
+	defer close(w.events)
+
+	buf := make([]byte, 64*(syscall.SizeofInotifyEvent+syscall.NAME_MAX+1))
+	for {
+		n, err := w.file.Read(buf)
+		if err != nil {
+			return
+		}
+
+		for offset := 0; offset+syscall.SizeofInotifyEvent <= n; {
+			event := (*syscall.InotifyEvent)(unsafe.Pointer(&buf[offset]))
+			nameStart := offset + syscall.SizeofInotifyEvent
+			name := string(bytes.TrimRight(buf[nameStart:nameStart+int(event.Len)], "\x00"))
+			offset = nameStart + int(event.Len)
+
+			w.mut.Lock()
+			dir, ok := w.dirs[event.Wd]
+			recursive := w.recursive[dir]
+			if event.Mask&syscall.IN_IGNORED != 0 {
+				delete(w.dirs, event.Wd)
+			}
+			w.mut.Unlock()
+			if !ok || name == "" {
+				continue
+			}
+
+			path := filepath.Join(dir, name)
+			isNewDir := event.Mask&syscall.IN_ISDIR != 0 &&
+				event.Mask&(syscall.IN_CREATE|syscall.IN_MOVED_TO) != 0
+			if recursive && isNewDir && !w.skipDir(path) {
+				// errors are ignored, the directory may already be gone
+				w.addTree(path)
+			}
+			select {
+			case w.events <- path:
+			case <-w.done:
+				return
+			}
+		}
+	}
+}
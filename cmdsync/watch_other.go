@@ -0,0 +1,103 @@
+//go:build !linux
+// +build !linux
+
+package cmdsync
+
+import (
+	"io/fs"
+	"path/filepath"
+	"time"
+)
+
+// pollInterval is how often watched files are checked for changes on
+// platforms without inotify
+const pollInterval = 500 * time.Millisecond
+
+// pollingWatcher is a fileWatcher that compares the modification times of the
+// watched files every pollInterval
+type pollingWatcher struct {
+	roots   []watchRoot
+	skipDir func(path string) bool
+	events  chan string
+	done    chan struct{}
+}
+
+func newFileWatcher(roots []watchRoot, skipDir func(path string) bool) (fileWatcher, error) {
+	w := &pollingWatcher{
+		roots:   roots,
+		skipDir: skipDir,
+		events:  make(chan string),
+		done:    make(chan struct{}),
+	}
+	go w.poll(w.scan())
+	return w, nil
+}
+
+func (w *pollingWatcher) Events() <-chan string {
+	return w.events
+}
+
+func (w *pollingWatcher) Close() error {
+	close(w.done)
+	return nil
+}
+
+// poll sends the paths of files that were added, removed or modified since
+// the previous scan until the watcher is closed
+func (w *pollingWatcher) poll(modTimes map[string]time.Time) {
+	defer close(w.events)
+	ticker := time.NewTicker(pollInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-w.done:
+			return
+		case <-ticker.C:
+		}
+
+		current := w.scan()
+		var changed []string
+		for path, modTime := range current {
+			if prev, ok := modTimes[path]; !ok || !prev.Equal(modTime) {
+				changed = append(changed, path)
+			}
+		}
+		for path := range modTimes {
+			if _, ok := current[path]; !ok {
+				changed = append(changed, path)
+			}
+		}
+		modTimes = current
+
+		for _, path := range changed {
+			select {
+			case w.events <- path:
+			case <-w.done:
+				return
+			}
+		}
+	}
+}
+
+// scan returns the modification times of all watched files
+func (w *pollingWatcher) scan() map[string]time.Time {
+	modTimes := make(map[string]time.Time)
+	for _, root := range w.roots {
+		filepath.WalkDir(root.path, func(path string, d fs.DirEntry, err error) error {
+			if err != nil {
+				return nil
+			}
+			if d.IsDir() {
+				if path != root.path && w.skipDir(path) {
+					return filepath.SkipDir
+				}
+				return nil
+			}
+			if info, err := d.Info(); err == nil {
+				modTimes[path] = info.ModTime()
+			}
+			return nil
+		})
+	}
+	return modTimes
+}
@@ -0,0 +1,216 @@
+package cmdsync
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestWatchFilter(t *testing.T) {
+	dir := t.TempDir()
+	for _, sub := range []string{"src", "node_modules"} {
+		if err := os.Mkdir(filepath.Join(dir, sub), 0755); err != nil {
+			t.Fatalf("making test directory: %s", err)
+		}
+	}
+	configFile := filepath.Join(t.TempDir(), "app.conf")
+	if err := os.WriteFile(configFile, nil, 0644); err != nil {
+		t.Fatalf("writing test file: %s", err)
+	}
+
+	tests := []struct {
+		name      string
+		config    WatchConfig
+		path      string
+		want      bool
+		wantSkip  bool // if the path is a directory that is not watched
+		wantError bool
+	}{
+		{
+			name:   "everything in the directory by default",
+			config: WatchConfig{},
+			path:   filepath.Join(dir, "src", "main.go"),
+			want:   true,
+		},
+		{
+			name:     ".git is ignored",
+			config:   WatchConfig{},
+			path:     filepath.Join(dir, ".git", "index"),
+			want:     false,
+			wantSkip: true,
+		},
+		{
+			name:   "outside of watched paths",
+			config: WatchConfig{Paths: []string{"src"}},
+			path:   filepath.Join(dir, "README.md"),
+			want:   false,
+		},
+		{
+			name:   "include matches file names",
+			config: WatchConfig{Include: []string{"*.go"}},
+			path:   filepath.Join(dir, "src", "main.go"),
+			want:   true,
+		},
+		{
+			name:   "include does not match",
+			config: WatchConfig{Include: []string{"*.go"}},
+			path:   filepath.Join(dir, "src", "main.go.swp"),
+			want:   false,
+		},
+		{
+			name:   "leading ** matches top level files",
+			config: WatchConfig{Include: []string{"**/*.go"}},
+			path:   filepath.Join(dir, "main.go"),
+			want:   true,
+		},
+		{
+			name:   "include with a directory",
+			config: WatchConfig{Include: []string{"src/*.go"}},
+			path:   filepath.Join(dir, "main.go"),
+			want:   false,
+		},
+		{
+			name:     "excluded directory name",
+			config:   WatchConfig{Exclude: []string{"node_modules"}},
+			path:     filepath.Join(dir, "node_modules", "left-pad", "index.js"),
+			want:     false,
+			wantSkip: true,
+		},
+		{
+			name:     "excluded path",
+			config:   WatchConfig{Exclude: []string{"node_modules/**"}},
+			path:     filepath.Join(dir, "node_modules", "index.js"),
+			want:     false,
+			wantSkip: true,
+		},
+		{
+			name:   "exclude wins over include",
+			config: WatchConfig{Include: []string{"*.go"}, Exclude: []string{"*_test.go"}},
+			path:   filepath.Join(dir, "src", "main_test.go"),
+			want:   false,
+		},
+		{
+			name:   "watched file outside of the directory",
+			config: WatchConfig{Paths: []string{configFile}, Include: []string{"*.go"}},
+			path:   configFile,
+			want:   true,
+		},
+		{
+			name:   "other file next to a watched file",
+			config: WatchConfig{Paths: []string{configFile}},
+			path:   filepath.Join(filepath.Dir(configFile), "other.conf"),
+			want:   false,
+		},
+		{
+			name:      "missing path",
+			config:    WatchConfig{Paths: []string{"potato"}},
+			wantError: true,
+		},
+		{
+			name:      "invalid pattern",
+			config:    WatchConfig{Include: []string{"[a-z"}},
+			wantError: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			filter, err := newWatchFilter(tt.config, dir)
+			if (err != nil) != tt.wantError {
+				t.Fatalf("want error %v, got %v", tt.wantError, err)
+			}
+			if err != nil {
+				return
+			}
+			if got := filter.matches(tt.path); got != tt.want {
+				t.Errorf("want matches(%q) %v, got %v", tt.path, tt.want, got)
+			}
+			if got := filter.skipDir(filepath.Dir(tt.path)); got != tt.wantSkip {
+				t.Errorf("want skipDir(%q) %v, got %v", filepath.Dir(tt.path), tt.wantSkip, got)
+			}
+		})
+	}
+}
+
+func TestGroup_Watch(t *testing.T) {
+	testShell := getInstalledShells(t)[0]
+	dir := t.TempDir()
+	if err := os.Mkdir(filepath.Join(dir, "src"), 0755); err != nil {
+		t.Fatalf("making test directory: %s", err)
+	}
+
+	var out syncBuffer
+	mustNewShellCmd := func(command string, opts ...ShellCmdOption) *ShellCmd {
+		cmd, err := NewShellCmd(testShell, command, append(opts, CmdDir(dir))...)
+		if err != nil {
+			t.Fatalf("malformed test, failed mustNewShellCmd(%s, opts...), err: %s", command, err)
+		}
+		cmd.stdout = &out
+		return cmd
+	}
+
+	group := NewGroup(
+		// a command that exits is restarted too, e.g. a build
+		mustNewShellCmd("echo built $(cat src/version 2>/dev/null)", Name("build"),
+			Watch(WatchConfig{
+				Include:           []string{"*.go", "version"},
+				Exclude:           []string{"*_test.go"},
+				Debounce:          100 * time.Millisecond,
+				RestartDependents: true,
+			}),
+		),
+		mustNewShellCmd("echo serving && sleep 10", Name("server"), DependsOn("build")),
+	)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	errs := make(chan error, 1)
+	go func() {
+		errs <- group.RunContext(ctx)
+	}()
+
+	waitForCount := func(want string, count int) {
+		deadline := time.Now().Add(5 * time.Second)
+		for strings.Count(out.String(), want) < count {
+			if time.Now().After(deadline) {
+				t.Fatalf("want output to contain %q %d times, got %q", want, count, out.String())
+			}
+			time.Sleep(50 * time.Millisecond)
+		}
+	}
+	writeFile := func(name, contents string) {
+		if err := os.WriteFile(filepath.Join(dir, "src", name), []byte(contents), 0644); err != nil {
+			t.Fatalf("writing test file: %s", err)
+		}
+	}
+	waitForCount("server | serving\n", 1)
+
+	// excluded files do not restart anything
+	writeFile("main_test.go", "package main")
+	time.Sleep(500 * time.Millisecond)
+	if got := strings.Count(out.String(), "build | built"); got != 1 {
+		t.Fatalf("want excluded file to not restart build, got output %q", out.String())
+	}
+
+	writeFile("version", "v2")
+	waitForCount("build | built v2\n", 1)
+	// the server depends on build and is restarted once build is done again
+	waitForCount("server | serving\n", 2)
+
+	// files in new directories are watched too
+	if err := os.Mkdir(filepath.Join(dir, "src", "pkg"), 0755); err != nil {
+		t.Fatalf("making test directory: %s", err)
+	}
+	time.Sleep(200 * time.Millisecond)
+	writeFile(filepath.Join("pkg", "pkg.go"), "package pkg")
+	waitForCount("build | built v2\n", 2)
+
+	cancel()
+	select {
+	case <-errs:
+	case <-time.After(5 * time.Second):
+		t.Fatalf("want group to finish once its context is cancelled")
+	}
+}
@@ -5,5 +5,5 @@ go 1.16
 require (
 	github.com/spf13/cobra v1.2.0
 	golang.org/x/sync v0.0.0-20210220032951-036812b2e83c
-	gopkg.in/yaml.v2 v2.4.0
+	gopkg.in/yaml.v3 v3.0.1
 )
@@ -2,7 +2,12 @@
 package cli
 
 import (
+	"context"
 	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
 
 	"github.com/alexchao26/oneterminal/cmdsync"
 	"github.com/alexchao26/oneterminal/color"
@@ -10,8 +15,12 @@ import (
 	"github.com/spf13/cobra"
 )
 
-// Init creates the root command by parsing all yaml configs from the
-// ~/.config/oneterminal directory and adding them to the root command.
+// Init creates the root command by parsing all yaml configs from the config
+// directories and any project-local .oneterminal.yml files or .oneterminal/
+// directories, then adding them to the root command.
+//
+// Config directories default to ~/.config/oneterminal, but can be set via the
+// --config-dir flag or the ONETERMINAL_CONFIG_PATH environment variable.
 //
 // All commands will be accessible via oneterminal <command-name>
 func Init(version string) (*cobra.Command, error) {
@@ -22,17 +31,31 @@ func Init(version string) (*cobra.Command, error) {
 It strives to reduce the number of terminal windows
 that need to be open.
 
-Config files live in ~/.config/oneterminal
+Config files live in ~/.config/oneterminal, or $XDG_CONFIG_HOME/oneterminal
+Set ONETERMINAL_CONFIG_PATH to a colon separated list of directories or use
+the --config-dir flag to search other directories
+Project-local configs are found by walking up from the current directory
+looking for .oneterminal.yml files or .oneterminal/ directories
 Run "oneterminal example" to generate an example config file`,
 	}
 
-	allConfigs, err := yaml.ParseAllConfigs()
+	// configs are parsed before cobra parses flags, so --config-dir is read from
+	// the raw args. The flag is only registered for help texts, completion and
+	// so cobra accepts it, its parsed value is never read
+	rootCmd.PersistentFlags().StringArray("config-dir", nil,
+		"directory to search for configs, can be repeated (default ~/.config/oneterminal)")
+	yaml.SetConfigDirs(configDirsFromArgs(os.Args[1:])...)
+
+	allConfigs, warnings, err := yaml.ParseAllConfigs()
 	if err != nil {
 		return nil, fmt.Errorf("parsing yml configs: %w", err)
 	}
-
-	if err := yaml.HasNameCollisions(allConfigs); err != nil {
-		return nil, err
+	// broken configs are skipped so the rest of the CLI keeps working, validate
+	// reports the same problems itself
+	if !quietWarnings(os.Args[1:]) {
+		for _, warning := range warnings {
+			fmt.Fprintf(os.Stderr, "Warning: skipping config: %s\n", warning)
+		}
 	}
 
 	generatedCommands := makeCommands(allConfigs)
@@ -40,9 +63,18 @@ Run "oneterminal example" to generate an example config file`,
 	rootCmd.AddCommand(generatedCommands...)
 
 	rootCmd.AddCommand(ExampleCmd)
+	rootCmd.AddCommand(makeNewCmd(allConfigs))
 	rootCmd.AddCommand(makeUpdateCmd(version))
 	rootCmd.AddCommand(makeVersionCmd(version))
 	rootCmd.AddCommand(makeListCmd(allConfigs))
+	rootCmd.AddCommand(makeValidateCmd())
+	rootCmd.AddCommand(makeSchemaCmd())
+	rootCmd.AddCommand(makeGraphCmd(allConfigs))
+	rootCmd.AddCommand(makeImportCmd())
+	rootCmd.AddCommand(makeExportCmd(allConfigs))
+	rootCmd.AddCommand(makeEditCmd(allConfigs))
+	rootCmd.AddCommand(makeDescribeCmd(allConfigs))
+	rootCmd.AddCommand(makeStatusCmd(allConfigs))
 
 	return rootCmd, nil
 }
@@ -52,6 +84,7 @@ func makeCommands(configs []yaml.OneTerminalConfig) []*cobra.Command {
 
 	for _, config := range configs {
 		config := config
+		flags := &runFlags{}
 
 		// create the final cobra command and add it to the root command
 		cobraCommand := &cobra.Command{
@@ -59,39 +92,51 @@ func makeCommands(configs []yaml.OneTerminalConfig) []*cobra.Command {
 			Short: config.Short,
 			Long:  config.Long,
 			Run: func(cmd *cobra.Command, args []string) {
-				group := cmdsync.NewGroup()
-
-				for i, cmd := range config.Commands {
-					var options []cmdsync.ShellCmdOption
-					if cmd.Name != "" {
-						options = append(options, cmdsync.Name(cmd.Name))
-						options = append(options, cmdsync.Color(color.ColorsList[i%len(color.ColorsList)]))
-					}
-					if cmd.CmdDir != "" {
-						options = append(options, cmdsync.CmdDir(cmd.CmdDir))
-					}
-					if cmd.Silence {
-						options = append(options, cmdsync.SilenceOutput())
-					}
-					if cmd.ReadyRegexp != "" {
-						options = append(options, cmdsync.ReadyPattern(cmd.ReadyRegexp))
-					}
-					if len(cmd.DependsOn) != 0 {
-						options = append(options, cmdsync.DependsOn(cmd.DependsOn...))
+				resolved, err := flags.resolve(cmd, config)
+				if err != nil {
+					fmt.Printf("running %q: %v\n", config.Name, err)
+					return
+				}
+				if flags.dryRun {
+					selected, err := flags.selectCommands(config)
+					if err == nil {
+						err = printPlan(os.Stdout, selected, flags.profile, flags.paramValues(cmd))
 					}
-					if cmd.Environment != nil {
-						options = append(options, cmdsync.Environment(cmd.Environment))
+					if err != nil {
+						fmt.Printf("planning %q: %v\n", config.Name, err)
 					}
+					return
+				}
+				if flags.profile != "" {
+					fmt.Printf("running %q with profile %q\n", config.Name, flags.profile)
+				}
+
+				group, err := makeGroup(resolved)
+				if err != nil {
+					fmt.Printf("running %q: %v\n", config.Name, err)
+					return
+				}
 
-					s, err := cmdsync.NewShellCmd(config.Shell, cmd.Command, options...)
+				if flags.metricsAddr != "" {
+					stop, err := serveMetrics(flags.metricsAddr, config.Name, group)
 					if err != nil {
-						panic(fmt.Sprintf("error making command %q: %v", cmd.Name, err))
+						fmt.Printf("running %q: %v\n", config.Name, err)
+						return
 					}
-
-					group.AddCommands(s)
+					defer stop()
+				}
+				if release, err := markRunning(config, group); err != nil {
+					fmt.Printf("marking %q as running: %v\n", config.Name, err)
+				} else {
+					defer release()
+				}
+				if !flags.noReload {
+					ctx, cancel := context.WithCancel(context.Background())
+					defer cancel()
+					go newReloader(cmd, flags, group, resolved).watch(ctx)
 				}
 
-				err := group.Run()
+				err = group.Run()
 				if err != nil {
 					fmt.Printf("running %q: %v\n", config.Name, err)
 				}
@@ -103,8 +148,264 @@ func makeCommands(configs []yaml.OneTerminalConfig) []*cobra.Command {
 			cobraCommand.Aliases = []string{config.Alias}
 		}
 
+		flags.register(cobraCommand, config)
+
 		cobraCommands = append(cobraCommands, cobraCommand)
 	}
 
 	return cobraCommands
 }
+
+// runFlags are the flags of a generated command that change how its config runs
+type runFlags struct {
+	params      map[string]*string // values of each param's flag, keyed by name
+	profile     string
+	only        []string
+	except      []string
+	dryRun      bool
+	noReload    bool
+	metricsAddr string
+}
+
+// register adds the flags to a config's generated command
+func (f *runFlags) register(cobraCommand *cobra.Command, config yaml.OneTerminalConfig) {
+	f.params = make(map[string]*string, len(config.Params))
+	for _, param := range config.Params {
+		usage := param.Description
+		if param.Required {
+			usage += " (required)"
+		}
+		f.params[param.Name] = cobraCommand.Flags().String(param.Name, param.Default, usage)
+	}
+
+	if len(config.Profiles) > 0 {
+		cobraCommand.Flags().StringVar(&f.profile, "profile", "",
+			fmt.Sprintf("profile to run with [%s]", strings.Join(config.ProfileNames(), ", ")))
+		cobraCommand.RegisterFlagCompletionFunc("profile",
+			func(_ *cobra.Command, _ []string, _ string) ([]string, cobra.ShellCompDirective) {
+				return config.ProfileNames(), cobra.ShellCompDirectiveNoFileComp
+			})
+	}
+
+	cobraCommand.Flags().StringSliceVar(&f.only, "only", nil,
+		"only run these commands and the commands they depend on")
+	cobraCommand.Flags().StringSliceVar(&f.except, "except", nil,
+		"do not run these commands")
+	cobraCommand.Flags().BoolVar(&f.dryRun, "dry-run", false,
+		"print the execution plan without running anything")
+	cobraCommand.Flags().BoolVar(&f.noReload, "no-reload", false,
+		"do not restart commands when the config's files change")
+	cobraCommand.Flags().StringVar(&f.metricsAddr, "metrics-addr", "",
+		"serve Prometheus metrics of the commands on this address, e.g. localhost:9090")
+	for _, name := range []string{"only", "except"} {
+		cobraCommand.RegisterFlagCompletionFunc(name,
+			func(_ *cobra.Command, _ []string, _ string) ([]string, cobra.ShellCompDirective) {
+				return config.CommandNames(), cobra.ShellCompDirectiveNoFileComp
+			})
+	}
+}
+
+// resolve applies the flags' profile, command selection and params to config
+func (f *runFlags) resolve(cobraCommand *cobra.Command, config yaml.OneTerminalConfig) (yaml.OneTerminalConfig, error) {
+	config, err := f.selectCommands(config)
+	if err != nil {
+		return config, err
+	}
+	return config.Render(f.paramValues(cobraCommand))
+}
+
+// selectCommands applies the flags' profile and command selection to config
+func (f *runFlags) selectCommands(config yaml.OneTerminalConfig) (yaml.OneTerminalConfig, error) {
+	config, err := config.ApplyProfile(f.profile)
+	if err != nil {
+		return config, err
+	}
+	return config.Select(f.only, f.except)
+}
+
+// paramValues returns the values of the param flags that were passed
+func (f *runFlags) paramValues(cobraCommand *cobra.Command) map[string]string {
+	params := make(map[string]string)
+	for name, value := range f.params {
+		if cobraCommand.Flags().Changed(name) {
+			params[name] = *value
+		}
+	}
+	return params
+}
+
+// makeGroup converts a rendered config's commands into a cmdsync.Group
+func makeGroup(config yaml.OneTerminalConfig) (*cmdsync.Group, error) {
+	group := cmdsync.NewGroup()
+
+	for i, cmd := range config.Commands {
+		s, err := makeShellCmd(config, i, cmd)
+		if err != nil {
+			return nil, err
+		}
+		group.AddCommands(s)
+	}
+
+	var before, after []*cmdsync.ShellCmd
+	for _, hooks := range config.HookConfigs() {
+		// hooks of included configs are prefixed with their config's name
+		kind := ""
+		if hooks.Path != config.Path {
+			kind = hooks.Name + " "
+		}
+		b, err := makeHooks(hooks, kind+"before", hooks.Before)
+		if err != nil {
+			return nil, err
+		}
+		a, err := makeHooks(hooks, kind+"after", hooks.After)
+		if err != nil {
+			return nil, err
+		}
+		before = append(before, b...)
+		after = append(a, after...)
+	}
+	if len(before) > 0 || len(after) > 0 {
+		group.AddHooks(before, after)
+	}
+
+	return group, nil
+}
+
+// makeHooks converts a rendered config's before or after hooks into ShellCmds
+// that run in the config file's directory
+func makeHooks(config yaml.OneTerminalConfig, kind string, hooks []string) ([]*cmdsync.ShellCmd, error) {
+	var cmds []*cmdsync.ShellCmd
+	shell, shellArgs := config.CommandShell(yaml.Command{})
+	for _, hook := range hooks {
+		s, err := cmdsync.NewShellCmd(shell, hook, cmdsync.ShellArgs(shellArgs...),
+			cmdsync.Name(kind), cmdsync.CmdDir(filepath.Dir(config.Path)))
+		if err != nil {
+			return nil, fmt.Errorf("making %s hook %q: %w", kind, hook, err)
+		}
+		cmds = append(cmds, s)
+	}
+	return cmds, nil
+}
+
+// makeShellCmd converts the i-th command of a rendered config into a ShellCmd
+func makeShellCmd(config yaml.OneTerminalConfig, i int, cmd yaml.Command) (*cmdsync.ShellCmd, error) {
+	shell, shellArgs := config.CommandShell(cmd)
+	options := []cmdsync.ShellCmdOption{cmdsync.ShellArgs(shellArgs...)}
+	if len(cmd.Argv) > 0 {
+		options = append(options, cmdsync.Argv(cmd.Argv...))
+	}
+	if cmd.Name != "" {
+		options = append(options, cmdsync.Name(cmd.Name))
+		options = append(options, cmdsync.Color(color.ColorsList[i%len(color.ColorsList)]))
+	}
+	if cmd.CmdDir != "" {
+		options = append(options, cmdsync.CmdDir(cmd.CmdDir))
+	}
+	if cmd.Silence {
+		options = append(options, cmdsync.SilenceOutput())
+	}
+	if cmd.ReadyRegexp != "" {
+		options = append(options, cmdsync.ReadyPattern(cmd.ReadyRegexp))
+	}
+	if cmd.ReadyCheck != "" {
+		options = append(options, cmdsync.ReadyCheck(cmd.ReadyCheck))
+	}
+	for _, dep := range cmd.DependsOn {
+		condition := cmdsync.ConditionReady
+		if dep.Condition != "" {
+			condition = cmdsync.Condition(dep.Condition)
+		}
+		options = append(options, cmdsync.DependsOnCondition(dep.Name, condition))
+	}
+	if cmd.Environment != nil {
+		options = append(options, cmdsync.Environment(cmd.Environment))
+	}
+	if len(cmd.Before) > 0 {
+		options = append(options, cmdsync.BeforeHooks(cmd.Before...))
+	}
+	if len(cmd.After) > 0 {
+		options = append(options, cmdsync.AfterHooks(cmd.After...))
+	}
+	if cmd.Watch != nil {
+		watch := cmdsync.WatchConfig{
+			Paths:             cmd.Watch.Paths,
+			Include:           cmd.Watch.Include,
+			Exclude:           cmd.Watch.Exclude,
+			RestartDependents: cmd.Watch.RestartDependents,
+		}
+		if cmd.Watch.Debounce != "" {
+			debounce, err := time.ParseDuration(cmd.Watch.Debounce)
+			if err != nil {
+				return nil, fmt.Errorf("making command %q: invalid watch debounce: %w", cmd.Name, err)
+			}
+			watch.Debounce = debounce
+		}
+		options = append(options, cmdsync.Watch(watch))
+	}
+	if cmd.Limits != nil {
+		limits, err := cmd.Limits.CmdsyncLimits()
+		if err != nil {
+			return nil, fmt.Errorf("making command %q: invalid limits: %w", cmd.Name, err)
+		}
+		options = append(options, cmdsync.ResourceLimits(limits))
+	}
+
+	s, err := cmdsync.NewShellCmd(shell, cmd.Command, options...)
+	if err != nil {
+		return nil, fmt.Errorf("making command %q: %w", cmd.Name, err)
+	}
+	return s, nil
+}
+
+// configLookup indexes configs by their names and aliases, and returns their
+// names for shell completion
+func configLookup(allConfigs []yaml.OneTerminalConfig) (map[string]yaml.OneTerminalConfig, []string) {
+	configsByName := make(map[string]yaml.OneTerminalConfig)
+	var names []string
+	for _, config := range allConfigs {
+		configsByName[config.Name] = config
+		if config.Alias != "" {
+			configsByName[config.Alias] = config
+		}
+		names = append(names, config.Name)
+	}
+	return configsByName, names
+}
+
+// quietWarnings returns true if args run a command that should not print
+// warnings about broken configs, i.e. validate and shell completions
+func quietWarnings(args []string) bool {
+	for i := 0; i < len(args); i++ {
+		if args[i] == "--config-dir" {
+			i++
+			continue
+		}
+		if strings.HasPrefix(args[i], "-") {
+			continue
+		}
+		switch args[i] {
+		case "validate", "lint", cobra.ShellCompRequestCmd, cobra.ShellCompNoDescRequestCmd:
+			return true
+		}
+		return false
+	}
+	return false
+}
+
+// configDirsFromArgs returns the values of all --config-dir flags in args
+func configDirsFromArgs(args []string) []string {
+	var dirs []string
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+		if arg == "--" {
+			break
+		}
+		if arg == "--config-dir" && i+1 < len(args) {
+			dirs = append(dirs, args[i+1])
+			i++
+		} else if strings.HasPrefix(arg, "--config-dir=") {
+			dirs = append(dirs, strings.TrimPrefix(arg, "--config-dir="))
+		}
+	}
+	return dirs
+}
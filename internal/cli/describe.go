@@ -0,0 +1,129 @@
+package cli
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/alexchao26/oneterminal/internal/yaml"
+	"github.com/spf13/cobra"
+)
+
+func makeDescribeCmd(allConfigs []yaml.OneTerminalConfig) *cobra.Command {
+	configsByName, names := configLookup(allConfigs)
+
+	return &cobra.Command{
+		Use:   "describe <name>",
+		Short: "Show a config's source file and the settings of each command",
+		Long: `Shows where a config is defined, its alias, shell, params and profiles, and a
+breakdown of each command's settings. Commands are shown as written, without
+templates rendered or profiles applied; use "oneterminal <name> --dry-run"
+to see exactly what would run.`,
+		Args:      cobra.ExactArgs(1),
+		ValidArgs: names,
+		RunE: func(_ *cobra.Command, args []string) error {
+			config, ok := configsByName[args[0]]
+			if !ok {
+				return fmt.Errorf("config %q does not exist", args[0])
+			}
+			describeConfig(os.Stdout, config)
+			return nil
+		},
+	}
+}
+
+// describeConfig writes a config's settings
+func describeConfig(w io.Writer, config yaml.OneTerminalConfig) {
+	fmt.Fprintf(w, "name:     %s\n", config.Name)
+	if config.Alias != "" {
+		fmt.Fprintf(w, "alias:    %s\n", config.Alias)
+	}
+	fmt.Fprintf(w, "file:     %s\n", config.Path)
+	fmt.Fprintf(w, "shell:    %s\n", formatShell(config.CommandShell(yaml.Command{})))
+	if config.Short != "" {
+		fmt.Fprintf(w, "short:    %s\n", config.Short)
+	}
+	if len(config.Include) > 0 {
+		fmt.Fprintf(w, "includes: %s\n", strings.Join(config.Include, ", "))
+	}
+	if len(config.Profiles) > 0 {
+		fmt.Fprintf(w, "profiles: %s\n", strings.Join(config.ProfileNames(), ", "))
+	}
+	if len(config.Before) > 0 {
+		fmt.Fprintf(w, "before:   %s\n", indentLines(strings.Join(config.Before, "\n"), "          "))
+	}
+	if len(config.After) > 0 {
+		fmt.Fprintf(w, "after:    %s\n", indentLines(strings.Join(config.After, "\n"), "          "))
+	}
+
+	if len(config.Params) > 0 {
+		fmt.Fprintf(w, "\nParams\n")
+		for _, param := range config.Params {
+			fmt.Fprintf(w, "  --%s", param.Name)
+			if param.Default != "" {
+				fmt.Fprintf(w, " (default %q)", param.Default)
+			}
+			if param.Required {
+				fmt.Fprintf(w, " (required)")
+			}
+			if param.Description != "" {
+				fmt.Fprintf(w, ": %s", param.Description)
+			}
+			fmt.Fprintln(w)
+		}
+	}
+
+	fmt.Fprintf(w, "\nCommands\n")
+	for _, cmd := range config.Commands {
+		name := cmd.Name
+		if name == "" {
+			name = "(unnamed)"
+		}
+		fmt.Fprintf(w, "  %s\n", name)
+		if len(cmd.Argv) > 0 {
+			fmt.Fprintf(w, "    argv:        %s\n", formatArgv(cmd.Argv[0], cmd.Argv[1:]))
+		} else {
+			fmt.Fprintf(w, "    command:     %s\n", indentLines(cmd.Command, "                 "))
+		}
+		if cmd.Shell != "" || len(cmd.ShellArgs) > 0 {
+			fmt.Fprintf(w, "    shell:       %s\n", formatShell(config.CommandShell(cmd)))
+		}
+		if cmd.CmdDir != "" {
+			fmt.Fprintf(w, "    directory:   %s\n", cmd.CmdDir)
+		}
+		if cmd.Source() != config.Path {
+			fmt.Fprintf(w, "    included:    %s\n", cmd.Source())
+		}
+		if len(cmd.DependsOn) > 0 {
+			fmt.Fprintf(w, "    depends-on:  %s\n", formatDependencies(cmd.DependsOn))
+		}
+		fmt.Fprintf(w, "    ready:       %s\n", readyCondition(cmd))
+		if cmd.Watch != nil {
+			fmt.Fprintf(w, "    watch:       %s\n", describeWatch(*cmd.Watch))
+		}
+		if cmd.Limits != nil {
+			fmt.Fprintf(w, "    limits:      %s\n", describeLimits(*cmd.Limits))
+		}
+		if len(cmd.Before) > 0 {
+			fmt.Fprintf(w, "    before:      %s\n", indentLines(strings.Join(cmd.Before, "\n"), "                 "))
+		}
+		if len(cmd.After) > 0 {
+			fmt.Fprintf(w, "    after:       %s\n", indentLines(strings.Join(cmd.After, "\n"), "                 "))
+		}
+		if len(cmd.Environment) > 0 {
+			fmt.Fprintf(w, "    environment: %s\n", formatEnvironment(cmd.Environment))
+		}
+		if cmd.Silence {
+			fmt.Fprintf(w, "    silenced:    true\n")
+		}
+		if cmd.Disabled {
+			fmt.Fprintf(w, "    disabled:    true\n")
+		}
+	}
+}
+
+// indentLines indents every line of a multi-line string after the first
+func indentLines(s, indent string) string {
+	return strings.ReplaceAll(strings.TrimRight(s, "\n"), "\n", "\n"+indent)
+}
@@ -0,0 +1,138 @@
+package cli
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/alexchao26/oneterminal/internal/yaml"
+)
+
+func TestDescribeConfig(t *testing.T) {
+	configDir := t.TempDir()
+	yaml.SetConfigDirs(configDir)
+	t.Cleanup(func() { yaml.SetConfigDirs() })
+
+	files := map[string]string{
+		"hello.yml": `name: hello
+commands:
+- command: echo hello
+`,
+		"db.yml": `name: db
+commands:
+- name: postgres
+  command: postgres -D data
+  ready-check: pg_isready
+`,
+		"shop.yml": `name: shop
+alias: s
+shell: bash
+short: runs the shop
+include: [db]
+before: [docker network create shop, mkdir -p tmp]
+params:
+- name: port
+  default: "8080"
+  description: api port
+- name: token
+  required: true
+profiles:
+  staging: {}
+  local: {}
+commands:
+- name: api
+  command: |
+    go run ./cmd/api
+    --port {{ .Params.port }}
+  directory: ~/code/shop
+  depends-on: [db.postgres]
+  ready-regexp: listening
+  environment:
+    B: "2"
+    A: "1"
+  after: [rm -rf tmp]
+  silence: true
+- name: search
+  argv: [rg, --json, "it's"]
+  disabled: true
+`,
+	}
+	for name, contents := range files {
+		if err := os.WriteFile(filepath.Join(configDir, name), []byte(contents), 0644); err != nil {
+			t.Fatalf("malformed test, writing config: %s", err)
+		}
+	}
+	configs, errs, err := yaml.ParseAllConfigs()
+	if err != nil || len(errs) > 0 {
+		t.Fatalf("malformed test, parsing configs: %v %v", err, errs)
+	}
+	configsByName, _ := configLookup(configs)
+
+	tests := []struct {
+		name string
+		want string
+	}{
+		{
+			name: "hello",
+			want: `name:     hello
+file:     <dir>/hello.yml
+shell:    zsh
+
+Commands
+  (unnamed)
+    command:     echo hello
+    ready:       exits successfully
+`,
+		},
+		{
+			name: "shop",
+			want: `name:     shop
+alias:    s
+file:     <dir>/shop.yml
+shell:    bash
+short:    runs the shop
+includes: db
+profiles: local, staging
+before:   docker network create shop
+          mkdir -p tmp
+
+Params
+  --port (default "8080"): api port
+  --token (required)
+
+Commands
+  db.postgres
+    command:     postgres -D data
+    shell:       zsh
+    included:    <dir>/db.yml
+    ready:       ` + "`pg_isready`" + ` succeeds or exits successfully
+  api
+    command:     go run ./cmd/api
+                 --port {{ .Params.port }}
+    directory:   ~/code/shop
+    depends-on:  db.postgres
+    ready:       output matches /listening/ or exits successfully
+    after:       rm -rf tmp
+    environment: A=1 B=2
+    silenced:    true
+  search
+    argv:        rg --json "it's"
+    ready:       exits successfully
+    disabled:    true
+`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var out bytes.Buffer
+			describeConfig(&out, configsByName[tt.name])
+			got := strings.ReplaceAll(out.String(), configDir, "<dir>")
+			if got != tt.want {
+				t.Errorf("want\n%s\ngot\n%s", tt.want, got)
+			}
+		})
+	}
+}
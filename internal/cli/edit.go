@@ -0,0 +1,97 @@
+package cli
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"github.com/alexchao26/oneterminal/internal/yaml"
+	"github.com/spf13/cobra"
+)
+
+func makeEditCmd(allConfigs []yaml.OneTerminalConfig) *cobra.Command {
+	configsByName, names := configLookup(allConfigs)
+
+	return &cobra.Command{
+		Use:   "edit <name|file>",
+		Short: "Open a config's yaml file in $EDITOR",
+		Long: `Opens the yaml file that defines a config in $VISUAL or $EDITOR (default vi),
+then validates the file once the editor exits. If problems are found, the file
+can be reopened to fix them.
+
+Config files that failed to load can be opened by their path.`,
+		Args:          cobra.ExactArgs(1),
+		ValidArgs:     names,
+		SilenceUsage:  true,
+		SilenceErrors: true,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			filename, err := editTarget(args[0], configsByName)
+			if err != nil {
+				return err
+			}
+			p := newPrompter(cmd.InOrStdin(), cmd.OutOrStdout())
+
+			for {
+				if err := openEditor(filename); err != nil {
+					return err
+				}
+
+				fileProblems, err := yaml.ValidateFile(filename)
+				if err != nil {
+					return fmt.Errorf("validating %s: %w", filename, err)
+				}
+				if len(fileProblems) == 0 {
+					fmt.Printf("%s is valid\n", filename)
+					return nil
+				}
+
+				for _, problem := range fileProblems {
+					fmt.Println(problem)
+				}
+				again, err := p.confirm("Edit again?", true)
+				if err != nil {
+					return err
+				}
+				if !again {
+					return fmt.Errorf("found %d problem(s) in %s", len(fileProblems), filename)
+				}
+			}
+		},
+	}
+}
+
+// editTarget returns the file of the config named arg, or arg itself if it is
+// the path of a file
+func editTarget(arg string, configsByName map[string]yaml.OneTerminalConfig) (string, error) {
+	if config, ok := configsByName[arg]; ok {
+		return config.Path, nil
+	}
+	if info, err := os.Stat(arg); err == nil && !info.IsDir() {
+		return filepath.Abs(arg)
+	}
+	return "", fmt.Errorf("config %q does not exist", arg)
+}
+
+// openEditor opens filename in the user's editor and waits for it to exit
+func openEditor(filename string) error {
+	editor := os.Getenv("VISUAL")
+	if editor == "" {
+		editor = os.Getenv("EDITOR")
+	}
+	if editor == "" {
+		editor = "vi"
+	}
+
+	// editors are often set with flags, e.g. EDITOR="code --wait"
+	args := strings.Fields(editor)
+	editorCmd := exec.Command(args[0], append(args[1:], filename)...)
+	editorCmd.Stdin = os.Stdin
+	editorCmd.Stdout = os.Stdout
+	editorCmd.Stderr = os.Stderr
+	if err := editorCmd.Run(); err != nil {
+		return fmt.Errorf("running editor %q: %w", editor, err)
+	}
+	return nil
+}
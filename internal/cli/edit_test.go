@@ -0,0 +1,67 @@
+package cli
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/alexchao26/oneterminal/internal/yaml"
+)
+
+func TestEditTarget(t *testing.T) {
+	dir := t.TempDir()
+	filename := filepath.Join(dir, "broken.yml")
+	if err := os.WriteFile(filename, []byte("name: [\n"), 0644); err != nil {
+		t.Fatalf("malformed test, writing config: %s", err)
+	}
+	configsByName := map[string]yaml.OneTerminalConfig{
+		"api": {Name: "api", Path: "/configs/api.yml"},
+	}
+
+	tests := []struct {
+		name        string
+		arg         string
+		want        string
+		wantErrPart string
+	}{
+		{
+			name: "config name",
+			arg:  "api",
+			want: "/configs/api.yml",
+		},
+		{
+			name: "file path",
+			arg:  filename,
+			want: filename,
+		},
+		{
+			name:        "directory",
+			arg:         dir,
+			wantErrPart: "does not exist",
+		},
+		{
+			name:        "missing",
+			arg:         "potato",
+			wantErrPart: `config "potato" does not exist`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := editTarget(tt.arg, configsByName)
+			if tt.wantErrPart != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErrPart) {
+					t.Fatalf("want error containing %q, got %v", tt.wantErrPart, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("want nil error, got %s", err)
+			}
+			if got != tt.want {
+				t.Errorf("want %q, got %q", tt.want, got)
+			}
+		})
+	}
+}
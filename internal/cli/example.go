@@ -2,20 +2,35 @@ package cli
 
 import (
 	"fmt"
+	"os"
 
 	"github.com/alexchao26/oneterminal/internal/yaml"
 	"github.com/spf13/cobra"
 )
 
-// ExampleCmd makes an example oneterminal config file
-// in the ~/.config/oneterminal directory
+// ExampleCmd makes an example oneterminal config file in the first config
+// directory, see yaml.ConfigDirs
 var ExampleCmd = &cobra.Command{
 	Use:   "example",
-	Short: "Makes a demo oneterminal config in ~/.config/oneterminal",
+	Short: "Makes a demo oneterminal config in the first config directory",
 	Run: func(cmd *cobra.Command, args []string) {
-		if err := yaml.WriteExampleConfig("example.yml"); err != nil {
+		examplePath, err := yaml.NewConfigPath("example.yml")
+		if err != nil {
 			panic(fmt.Sprintf("Error generating example config :( %s", err))
 		}
-		fmt.Println("Example file generated at ~/.config/oneterminal/example.yml")
+		if _, err := os.Stat(examplePath); err == nil {
+			p := newPrompter(cmd.InOrStdin(), cmd.OutOrStdout())
+			overwrite, err := p.confirm(fmt.Sprintf("%s already exists, overwrite it?", examplePath), false)
+			if err != nil || !overwrite {
+				fmt.Println("Example file not generated")
+				return
+			}
+		}
+
+		examplePath, err = yaml.WriteExampleConfig("example.yml")
+		if err != nil {
+			panic(fmt.Sprintf("Error generating example config :( %s", err))
+		}
+		fmt.Printf("Example file generated at %s\n", examplePath)
 	},
 }
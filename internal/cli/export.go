@@ -0,0 +1,132 @@
+package cli
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/alexchao26/oneterminal/internal/yaml"
+	"github.com/spf13/cobra"
+)
+
+func makeExportCmd(allConfigs []yaml.OneTerminalConfig) *cobra.Command {
+	var format, profile, output string
+	var params map[string]string
+	var force bool
+
+	configsByName, names := configLookup(allConfigs)
+
+	exportCmd := &cobra.Command{
+		Use:   "export <name>",
+		Short: "Convert a config into a Procfile, systemd units or a shell script",
+		Long: `Converts a config into a form that runs without oneterminal.
+
+  - procfile: a Procfile with a process for each command. Procfiles start
+    every process at once, so depends-on and ready conditions are lost
+  - systemd: a systemd user service for each command and a target that starts
+    all of them, with depends-on and ready conditions as unit dependencies
+  - sh: a standalone bash script that starts commands in dependency order and
+    waits for them to be ready
+
+The config's profile and params are applied before exporting, e.g.
+  oneterminal export <name> --format systemd --param port=8080 -o ~/.config/systemd/user`,
+		Args:      cobra.ExactArgs(1),
+		ValidArgs: names,
+		RunE: func(_ *cobra.Command, args []string) error {
+			config, ok := configsByName[args[0]]
+			if !ok {
+				return fmt.Errorf("config %q does not exist", args[0])
+			}
+			config, err := config.ApplyProfile(profile)
+			if err != nil {
+				return err
+			}
+			config, err = config.Render(params)
+			if err != nil {
+				return err
+			}
+
+			var contents []byte
+			var warnings []string
+			switch format {
+			case "procfile":
+				contents, warnings, err = yaml.ToProcfile(config)
+			case "sh":
+				contents, err = yaml.ToShellScript(config)
+			case "systemd":
+				var units []yaml.SystemdUnit
+				units, warnings, err = yaml.ToSystemd(config)
+				if err != nil {
+					return fmt.Errorf("exporting %q: %w", config.Name, err)
+				}
+				printWarnings(warnings)
+				return writeUnits(units, output, force)
+			default:
+				return fmt.Errorf("unknown format %q, use procfile|systemd|sh", format)
+			}
+			if err != nil {
+				return fmt.Errorf("exporting %q: %w", config.Name, err)
+			}
+			printWarnings(warnings)
+
+			if output == "" {
+				fmt.Print(string(contents))
+				return nil
+			}
+			perm := os.FileMode(0644)
+			if format == "sh" {
+				perm = 0755
+			}
+			if err := writeFile(output, contents, perm, force); err != nil {
+				return err
+			}
+			fmt.Fprintf(os.Stderr, "%q exported to %s\n", config.Name, output)
+			return nil
+		},
+	}
+
+	exportCmd.Flags().StringVarP(&format, "format", "f", "", "output format: procfile|systemd|sh")
+	exportCmd.MarkFlagRequired("format")
+	exportCmd.RegisterFlagCompletionFunc("format",
+		func(_ *cobra.Command, _ []string, _ string) ([]string, cobra.ShellCompDirective) {
+			return []string{"procfile", "systemd", "sh"}, cobra.ShellCompDirectiveNoFileComp
+		})
+	exportCmd.Flags().StringVar(&profile, "profile", "", "profile to apply before exporting")
+	exportCmd.Flags().StringToStringVar(&params, "param", nil, "param values, e.g. --param name=value")
+	exportCmd.Flags().StringVarP(&output, "output", "o", "",
+		"file to write to, or directory for systemd units (default: stdout)")
+	exportCmd.Flags().BoolVar(&force, "force", false, "overwrite output files that exist")
+
+	return exportCmd
+}
+
+// writeUnits writes systemd units into dir, or prints them if dir is empty
+func writeUnits(units []yaml.SystemdUnit, dir string, force bool) error {
+	if dir == "" {
+		for i, unit := range units {
+			if i > 0 {
+				fmt.Println()
+			}
+			fmt.Printf("# %s\n%s", unit.Name, unit.Contents)
+		}
+		return nil
+	}
+
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+	for _, unit := range units {
+		filename := filepath.Join(dir, unit.Name)
+		if err := writeFile(filename, unit.Contents, 0644, force); err != nil {
+			return err
+		}
+		fmt.Fprintf(os.Stderr, "Wrote %s\n", filename)
+	}
+	return nil
+}
+
+func printWarnings(warnings []string) {
+	for _, warning := range warnings {
+		fmt.Fprintf(os.Stderr, "Warning: %s\n", warning)
+	}
+}
@@ -0,0 +1,176 @@
+package cli
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/alexchao26/oneterminal/internal/yaml"
+	"github.com/spf13/cobra"
+)
+
+func makeGraphCmd(allConfigs []yaml.OneTerminalConfig) *cobra.Command {
+	var format, profile string
+
+	configsByName, names := configLookup(allConfigs)
+
+	graphCmd := &cobra.Command{
+		Use:   "graph <name>",
+		Short: "Print the dependency graph of a config's commands",
+		Long: `Prints the dependency graph of a config's commands, annotating each command
+with the condition that makes it ready for its dependents.
+
+Formats are an ASCII tree (default), Graphviz DOT and Mermaid, e.g.
+  oneterminal graph <name> --format dot | dot -Tpng > graph.png`,
+		Args:      cobra.ExactArgs(1),
+		ValidArgs: names,
+		RunE: func(_ *cobra.Command, args []string) error {
+			config, ok := configsByName[args[0]]
+			if !ok {
+				return fmt.Errorf("config %q does not exist", args[0])
+			}
+			config, err := config.ApplyProfile(profile)
+			if err != nil {
+				return err
+			}
+			// surface missing dependencies and cycles before drawing anything
+			if _, err := config.DependencyWaves(); err != nil {
+				return err
+			}
+
+			graph := newDependencyGraph(config)
+			switch format {
+			case "tree":
+				graph.writeTree(os.Stdout)
+			case "dot":
+				graph.writeDOT(os.Stdout)
+			case "mermaid":
+				graph.writeMermaid(os.Stdout)
+			default:
+				return fmt.Errorf("unknown format %q, use tree|dot|mermaid", format)
+			}
+			return nil
+		},
+	}
+
+	graphCmd.Flags().StringVarP(&format, "format", "f", "tree", "output format: tree|dot|mermaid")
+	graphCmd.Flags().StringVar(&profile, "profile", "", "profile to apply before graphing")
+
+	return graphCmd
+}
+
+// dependencyGraph of a config's commands, where edges point from a command to
+// the commands that depend on it, i.e. in startup order
+type dependencyGraph struct {
+	name       string
+	commands   []yaml.Command
+	dependents map[int][]int // indexes of commands, keyed by dependency index
+}
+
+func newDependencyGraph(config yaml.OneTerminalConfig) dependencyGraph {
+	g := dependencyGraph{
+		name:       config.Name,
+		commands:   config.Commands,
+		dependents: make(map[int][]int),
+	}
+
+	indexes := make(map[string]int, len(config.Commands))
+	for i, cmd := range config.Commands {
+		indexes[cmd.Name] = i
+	}
+	for i, cmd := range config.Commands {
+		for _, dep := range cmd.DependsOn {
+			g.dependents[indexes[dep.Name]] = append(g.dependents[indexes[dep.Name]], i)
+		}
+	}
+
+	return g
+}
+
+// label of a command, unnamed commands are numbered by their position
+func (g dependencyGraph) label(i int) string {
+	if g.commands[i].Name == "" {
+		return fmt.Sprintf("(unnamed #%d)", i+1)
+	}
+	return g.commands[i].Name
+}
+
+// writeTree draws each command below the commands it depends on. Commands with
+// multiple dependencies appear multiple times, but only the first occurrence
+// lists its dependents.
+func (g dependencyGraph) writeTree(w io.Writer) {
+	fmt.Fprintln(w, g.name)
+
+	var roots []int
+	for i, cmd := range g.commands {
+		if len(cmd.DependsOn) == 0 {
+			roots = append(roots, i)
+		}
+	}
+
+	drawn := make(map[int]bool, len(g.commands))
+	var draw func(nodes []int, indent string)
+	draw = func(nodes []int, indent string) {
+		for n, i := range nodes {
+			branch, childIndent := "├── ", "│   "
+			if n == len(nodes)-1 {
+				branch, childIndent = "└── ", "    "
+			}
+
+			if drawn[i] {
+				fmt.Fprintf(w, "%s%s%s (see above)\n", indent, branch, g.label(i))
+				continue
+			}
+			drawn[i] = true
+			fmt.Fprintf(w, "%s%s%s [ready: %s]\n", indent, branch, g.label(i), readyCondition(g.commands[i]))
+			draw(g.dependents[i], indent+childIndent)
+		}
+	}
+	draw(roots, "")
+}
+
+// writeDOT writes the graph in Graphviz's DOT language
+func (g dependencyGraph) writeDOT(w io.Writer) {
+	fmt.Fprintf(w, "digraph %s {\n", dotQuote(g.name))
+	fmt.Fprintln(w, "  rankdir=LR;")
+	fmt.Fprintln(w, "  node [shape=box];")
+	for i, cmd := range g.commands {
+		label := g.label(i) + "\nready: " + readyCondition(cmd)
+		fmt.Fprintf(w, "  n%d [label=%s];\n", i, dotQuote(label))
+	}
+	for i := range g.commands {
+		for _, dependent := range g.dependents[i] {
+			fmt.Fprintf(w, "  n%d -> n%d;\n", i, dependent)
+		}
+	}
+	fmt.Fprintln(w, "}")
+}
+
+// writeMermaid writes the graph as a Mermaid flowchart
+func (g dependencyGraph) writeMermaid(w io.Writer) {
+	fmt.Fprintln(w, "graph LR")
+	for i, cmd := range g.commands {
+		label := mermaidEscape(g.label(i)) + "<br/>ready: " + mermaidEscape(readyCondition(cmd))
+		fmt.Fprintf(w, "  n%d[\"%s\"]\n", i, label)
+	}
+	for i := range g.commands {
+		for _, dependent := range g.dependents[i] {
+			fmt.Fprintf(w, "  n%d --> n%d\n", i, dependent)
+		}
+	}
+}
+
+// mermaidEscape replaces the characters that end or break a quoted Mermaid
+// label with entity codes
+func mermaidEscape(s string) string {
+	return strings.NewReplacer(`"`, "#quot;", "<", "#lt;", ">", "#gt;").Replace(s)
+}
+
+// dotQuote makes a DOT string literal, newlines become centered line breaks
+func dotQuote(s string) string {
+	s = strings.ReplaceAll(s, `\`, `\\`)
+	s = strings.ReplaceAll(s, `"`, `\"`)
+	s = strings.ReplaceAll(s, "\n", `\n`)
+	return `"` + s + `"`
+}
@@ -0,0 +1,84 @@
+package cli
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/alexchao26/oneterminal/internal/yaml"
+)
+
+func TestDependencyGraph(t *testing.T) {
+	// a diamond with names and ready-regexps that need quoting
+	config := yaml.OneTerminalConfig{
+		Name: `my "app"`,
+		Commands: []yaml.Command{
+			{Name: "db", ReadyRegexp: `ready on "\d+" <ok>`},
+			{Name: "api.v1", DependsOn: []yaml.Dependency{{Name: "db"}}},
+			{Name: "worker", DependsOn: []yaml.Dependency{{Name: "db", Condition: "completed"}}},
+			{Name: "web", DependsOn: []yaml.Dependency{{Name: "api.v1"}, {Name: "worker"}}},
+			{Command: "echo unnamed"},
+		},
+	}
+
+	tests := []struct {
+		name  string
+		write func(dependencyGraph, *bytes.Buffer)
+		want  string
+	}{
+		{
+			name:  "tree",
+			write: func(g dependencyGraph, b *bytes.Buffer) { g.writeTree(b) },
+			want: `my "app"
+├── db [ready: output matches /ready on "\d+" <ok>/ or exits successfully]
+│   ├── api.v1 [ready: exits successfully]
+│   │   └── web [ready: exits successfully]
+│   └── worker [ready: exits successfully]
+│       └── web (see above)
+└── (unnamed #5) [ready: exits successfully]
+`,
+		},
+		{
+			name:  "dot",
+			write: func(g dependencyGraph, b *bytes.Buffer) { g.writeDOT(b) },
+			want: `digraph "my \"app\"" {
+  rankdir=LR;
+  node [shape=box];
+  n0 [label="db\nready: output matches /ready on \"\\d+\" <ok>/ or exits successfully"];
+  n1 [label="api.v1\nready: exits successfully"];
+  n2 [label="worker\nready: exits successfully"];
+  n3 [label="web\nready: exits successfully"];
+  n4 [label="(unnamed #5)\nready: exits successfully"];
+  n0 -> n1;
+  n0 -> n2;
+  n1 -> n3;
+  n2 -> n3;
+}
+`,
+		},
+		{
+			name:  "mermaid",
+			write: func(g dependencyGraph, b *bytes.Buffer) { g.writeMermaid(b) },
+			want: `graph LR
+  n0["db<br/>ready: output matches /ready on #quot;\d+#quot; #lt;ok#gt;/ or exits successfully"]
+  n1["api.v1<br/>ready: exits successfully"]
+  n2["worker<br/>ready: exits successfully"]
+  n3["web<br/>ready: exits successfully"]
+  n4["(unnamed #5)<br/>ready: exits successfully"]
+  n0 --> n1
+  n0 --> n2
+  n1 --> n3
+  n2 --> n3
+`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var out bytes.Buffer
+			tt.write(newDependencyGraph(config), &out)
+			if got := out.String(); got != tt.want {
+				t.Errorf("want:\n%s\ngot:\n%s", tt.want, got)
+			}
+		})
+	}
+}
@@ -0,0 +1,121 @@
+package cli
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/alexchao26/oneterminal/internal/yaml"
+	"github.com/spf13/cobra"
+)
+
+func makeImportCmd() *cobra.Command {
+	var name, output string
+	var force bool
+
+	// write prints the config or writes it to the output file
+	write := func(config yaml.OneTerminalConfig) error {
+		contents, err := yaml.Marshal(config)
+		if err != nil {
+			return fmt.Errorf("marshalling config: %w", err)
+		}
+		if output == "" {
+			fmt.Print(string(contents))
+			return nil
+		}
+
+		if err := writeFile(output, contents, 0644, force); err != nil {
+			return err
+		}
+		fmt.Fprintf(os.Stderr, "Config %q written to %s\n", config.Name, output)
+		return nil
+	}
+
+	importCmd := &cobra.Command{
+		Use:   "import",
+		Short: "Generate a config from a Procfile or docker-compose file",
+		Long: `Generates a oneterminal config from a Procfile or docker-compose file. The
+config is printed, or written to a file with --output, e.g.
+  oneterminal import compose docker-compose.yml -o ~/.config/oneterminal/app.yml`,
+	}
+
+	procfileCmd := &cobra.Command{
+		Use:   "procfile <file>",
+		Short: "Generate a config from a Procfile",
+		Long: `Generates a config with a command for each process of a Procfile. Commands
+run in the Procfile's directory.`,
+		Args: cobra.ExactArgs(1),
+		RunE: func(_ *cobra.Command, args []string) error {
+			f, err := os.Open(args[0])
+			if err != nil {
+				return err
+			}
+			defer f.Close()
+
+			dir, err := filepath.Abs(filepath.Dir(args[0]))
+			if err != nil {
+				return err
+			}
+			configName := name
+			if configName == "" {
+				configName = filepath.Base(dir)
+			}
+
+			config, err := yaml.FromProcfile(f, configName, dir)
+			if err != nil {
+				return fmt.Errorf("importing %s: %w", args[0], err)
+			}
+			return write(config)
+		},
+	}
+
+	composeCmd := &cobra.Command{
+		Use:   "compose <file>",
+		Short: "Generate a config from a docker-compose file",
+		Long: `Generates a config with a command for each service of a docker-compose file,
+which runs the service with "docker compose run".
+
+  - environment and env_file become the command's environment, which is passed
+    to the service's container
+  - depends_on becomes depends-on
+  - healthchecks become a ready-check that runs in the service's container`,
+		Args: cobra.ExactArgs(1),
+		RunE: func(_ *cobra.Command, args []string) error {
+			config, warnings, err := yaml.FromCompose(args[0])
+			if err != nil {
+				return fmt.Errorf("importing %s: %w", args[0], err)
+			}
+			if name != "" {
+				config.Name = name
+			}
+			printWarnings(warnings)
+			return write(config)
+		},
+	}
+
+	importCmd.PersistentFlags().StringVar(&name, "name", "", "name of the config (default: the file's directory name)")
+	importCmd.PersistentFlags().StringVarP(&output, "output", "o", "", "file to write the config to (default: stdout)")
+	importCmd.PersistentFlags().BoolVar(&force, "force", false, "overwrite the output file if it exists")
+	importCmd.AddCommand(procfileCmd, composeCmd)
+
+	return importCmd
+}
+
+// writeFile writes contents to filename, refusing to overwrite an existing file
+// unless force is set
+func writeFile(filename string, contents []byte, perm os.FileMode, force bool) error {
+	flags := os.O_WRONLY | os.O_CREATE | os.O_EXCL
+	if force {
+		flags = os.O_WRONLY | os.O_CREATE | os.O_TRUNC
+	}
+	f, err := os.OpenFile(filename, flags, perm)
+	if os.IsExist(err) {
+		return fmt.Errorf("%s already exists, use --force to overwrite it", filename)
+	}
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	_, err = f.Write(contents)
+	return err
+}
@@ -1,23 +1,65 @@
 package cli
 
 import (
+	"encoding/json"
 	"fmt"
+	"io"
 	"os"
+	"strings"
 	"text/tabwriter"
 
 	"github.com/alexchao26/oneterminal/internal/yaml"
 	"github.com/spf13/cobra"
 )
 
+// listedConfig is a config as printed by list --json and --yaml
+type listedConfig struct {
+	Name         string   `json:"name" yaml:"name"`
+	Alias        string   `json:"alias,omitempty" yaml:"alias,omitempty"`
+	Short        string   `json:"short,omitempty" yaml:"short,omitempty"`
+	File         string   `json:"file" yaml:"file"`
+	NumCommands  int      `json:"num_commands" yaml:"num_commands"`
+	CommandNames []string `json:"command_names" yaml:"command_names"`
+	Running      bool     `json:"running" yaml:"running"`
+	PID          int      `json:"pid,omitempty" yaml:"pid,omitempty"`
+}
+
 func makeListCmd(allConfigs []yaml.OneTerminalConfig) *cobra.Command {
-	return &cobra.Command{
+	var asJSON, asYAML, wide bool
+
+	listCmd := &cobra.Command{
 		Use:     "list",
 		Aliases: []string{"ls"},
 		Short:   "List only configured commands",
-		Long: `Lists the names of all commands configured in ~/.config/oneterminal/{file}.yaml
+		Long: `Lists the names of all commands configured in the config directories
+(~/.config/oneterminal by default) and project-local configs
+
+Excludes built in commands.
+
+--json and --yaml print each config's alias, file, commands and whether it is
+currently running, for scripts. --wide prints each command's settings, and
+marks running configs with a *.`,
+		Args: cobra.NoArgs,
+		RunE: func(_ *cobra.Command, _ []string) error {
+			switch {
+			case asJSON && asYAML:
+				return fmt.Errorf("--json and --yaml cannot be used together")
+			case asJSON:
+				encoder := json.NewEncoder(os.Stdout)
+				encoder.SetIndent("", "  ")
+				return encoder.Encode(listConfigs(allConfigs))
+			case asYAML:
+				contents, err := yaml.Marshal(listConfigs(allConfigs))
+				if err != nil {
+					return err
+				}
+				fmt.Print(string(contents))
+				return nil
+			case wide:
+				listWide(os.Stdout, allConfigs)
+				return nil
+			}
 
-Excludes built in commands.`,
-		Run: func(_ *cobra.Command, _ []string) {
 			fmt.Println("Configured commands (runable via `oneterminal <name>`)")
 			fmt.Println()
 			w := tabwriter.NewWriter(os.Stdout, 0, 8, 0, '\t', 0)
@@ -25,6 +67,80 @@ Excludes built in commands.`,
 				fmt.Fprintf(w, "%s:\t%s\n", config.Name, config.Short)
 			}
 			w.Flush()
+			return nil
 		},
 	}
+
+	listCmd.Flags().BoolVar(&asJSON, "json", false, "print configs as json")
+	listCmd.Flags().BoolVar(&asYAML, "yaml", false, "print configs as yaml")
+	listCmd.Flags().BoolVarP(&wide, "wide", "w", false, "print the settings of each command")
+
+	return listCmd
+}
+
+func listConfigs(allConfigs []yaml.OneTerminalConfig) []listedConfig {
+	// an empty list rather than null when there are no configs
+	listed := []listedConfig{}
+	for _, config := range allConfigs {
+		state, running := runningState(config)
+		l := listedConfig{
+			Name:         config.Name,
+			Alias:        config.Alias,
+			Short:        config.Short,
+			File:         config.Path,
+			NumCommands:  len(config.Commands),
+			CommandNames: config.CommandNames(),
+			Running:      running,
+		}
+		if running {
+			l.PID = state.PID
+		}
+		listed = append(listed, l)
+	}
+	return listed
+}
+
+// listWide writes every config with a table of its commands
+func listWide(out io.Writer, allConfigs []yaml.OneTerminalConfig) {
+	for i, config := range allConfigs {
+		if i > 0 {
+			fmt.Fprintln(out)
+		}
+
+		heading := config.Name
+		if config.Alias != "" {
+			heading += fmt.Sprintf(" (alias %s)", config.Alias)
+		}
+		if state, running := runningState(config); running {
+			heading = fmt.Sprintf("* %s, running since %s (pid %d)",
+				heading, state.Started.Format("2006-01-02 15:04:05"), state.PID)
+		}
+		fmt.Fprintln(out, heading)
+		if config.Short != "" {
+			fmt.Fprintf(out, "  %s\n", config.Short)
+		}
+		fmt.Fprintf(out, "  file: %s\n", config.Path)
+
+		w := tabwriter.NewWriter(out, 0, 8, 2, ' ', 0)
+		fmt.Fprintln(w, "  COMMAND\tDIRECTORY\tDEPENDS-ON\tREADY")
+		for _, cmd := range config.Commands {
+			name := cmd.Name
+			if name == "" {
+				name = "(unnamed)"
+			}
+			if cmd.Disabled {
+				name += " (disabled)"
+			}
+			fmt.Fprintf(w, "  %s\t%s\t%s\t%s\n", name, orDash(cmd.CmdDir),
+				orDash(strings.Join(cmd.DependencyNames(), ",")), readyCondition(cmd))
+		}
+		w.Flush()
+	}
+}
+
+func orDash(s string) string {
+	if s == "" {
+		return "-"
+	}
+	return s
 }
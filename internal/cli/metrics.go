@@ -0,0 +1,137 @@
+package cli
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/alexchao26/oneterminal/cmdsync"
+)
+
+// commandMetrics are the metrics with one sample per command. Commands without
+// a value, e.g. the usage of commands that are not running, are left out
+var commandMetrics = []struct {
+	name, kind, help string
+	value            func(cmdsync.CommandStatus) (float64, bool)
+}{
+	{"oneterminal_command_up", "gauge", "Whether the command's process is running.",
+		func(s cmdsync.CommandStatus) (float64, bool) { return boolValue(s.Running), true }},
+	{"oneterminal_command_ready", "gauge", "Whether the command is ready, so its dependents can start.",
+		func(s cmdsync.CommandStatus) (float64, bool) { return boolValue(s.Ready), true }},
+	{"oneterminal_command_restarts_total", "counter", "Times the command was restarted or reloaded.",
+		func(s cmdsync.CommandStatus) (float64, bool) { return float64(s.Restarts), true }},
+	{"oneterminal_command_output_lines_total", "counter", "Lines of output printed by the command, including silenced output.",
+		func(s cmdsync.CommandStatus) (float64, bool) { return float64(s.Lines), true }},
+	{"oneterminal_command_cpu_seconds", "gauge", "CPU time used by the command's running processes and the children they waited for, which drops as processes exit.",
+		func(s cmdsync.CommandStatus) (float64, bool) {
+			if s.Usage == nil {
+				return 0, false
+			}
+			return s.Usage.CPUTime.Seconds(), true
+		}},
+	{"oneterminal_command_memory_rss_bytes", "gauge", "Resident memory of the command's processes.",
+		func(s cmdsync.CommandStatus) (float64, bool) {
+			if s.Usage == nil {
+				return 0, false
+			}
+			return float64(s.Usage.RSS), true
+		}},
+	{"oneterminal_command_threads", "gauge", "Threads of the command's processes.",
+		func(s cmdsync.CommandStatus) (float64, bool) {
+			if s.Usage == nil {
+				return 0, false
+			}
+			return float64(s.Usage.Threads), true
+		}},
+	{"oneterminal_command_children", "gauge", "Processes in the command's process group other than its own.",
+		func(s cmdsync.CommandStatus) (float64, bool) {
+			if s.Usage == nil {
+				return 0, false
+			}
+			return float64(s.Usage.Children), true
+		}},
+}
+
+// serveMetrics serves the metrics of a running config's Group on addr until
+// the returned function is called
+func serveMetrics(addr, configName string, group *cmdsync.Group) (func(), error) {
+	listener, err := net.Listen("tcp", addr)
+	if err != nil {
+		return nil, fmt.Errorf("serving metrics: %w", err)
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/metrics", func(w http.ResponseWriter, _ *http.Request) {
+		var buf bytes.Buffer
+		writeMetrics(&buf, configName, group.Status())
+		w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+		w.Write(buf.Bytes())
+	})
+	server := &http.Server{Handler: mux}
+	go func() {
+		// Serve always returns an error, ErrServerClosed once stopped
+		if err := server.Serve(listener); err != http.ErrServerClosed {
+			fmt.Printf("serving metrics of %q stopped: %v\n", configName, err)
+		}
+	}()
+
+	return func() { server.Close() }, nil
+}
+
+// writeMetrics writes the state of a config's commands in the Prometheus text
+// exposition format
+func writeMetrics(w io.Writer, configName string, statuses []cmdsync.CommandStatus) {
+	labels := make([]string, len(statuses))
+	for i, status := range statuses {
+		name := status.Name
+		if name == "" {
+			// unnamed commands need distinct labels
+			name = fmt.Sprintf("(unnamed %d)", i)
+		}
+		labels[i] = fmt.Sprintf(`config="%s",command="%s"`, labelValue(configName), labelValue(name))
+	}
+
+	for _, metric := range commandMetrics {
+		fmt.Fprintf(w, "# HELP %s %s\n", metric.name, metric.help)
+		fmt.Fprintf(w, "# TYPE %s %s\n", metric.name, metric.kind)
+		for i, status := range statuses {
+			if value, ok := metric.value(status); ok {
+				fmt.Fprintf(w, "%s{%s} %s\n", metric.name, labels[i], formatValue(value))
+			}
+		}
+	}
+
+	fmt.Fprintf(w, "# HELP oneterminal_command_exits_total Times the command's process exited, by exit code, 128+n for signal n.\n")
+	fmt.Fprintf(w, "# TYPE oneterminal_command_exits_total counter\n")
+	for i, status := range statuses {
+		var codes []int
+		for code := range status.Exits {
+			codes = append(codes, code)
+		}
+		sort.Ints(codes)
+		for _, code := range codes {
+			fmt.Fprintf(w, "oneterminal_command_exits_total{%s,code=\"%d\"} %d\n", labels[i], code, status.Exits[code])
+		}
+	}
+}
+
+// labelValue escapes a label value, see the Prometheus text format
+func labelValue(s string) string {
+	return strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`).Replace(s)
+}
+
+func formatValue(v float64) string {
+	return strconv.FormatFloat(v, 'f', -1, 64)
+}
+
+func boolValue(b bool) float64 {
+	if b {
+		return 1
+	}
+	return 0
+}
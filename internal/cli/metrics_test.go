@@ -0,0 +1,88 @@
+package cli
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"github.com/alexchao26/oneterminal/cmdsync"
+)
+
+func TestWriteMetrics(t *testing.T) {
+	statuses := []cmdsync.CommandStatus{
+		{
+			Name:     `api "v2"`,
+			Running:  true,
+			Ready:    true,
+			Restarts: 2,
+			Exits:    map[int]uint64{130: 1, 0: 3, 1: 2},
+			Lines:    42,
+			Usage: &cmdsync.Usage{
+				CPUTime:  1500 * time.Millisecond,
+				RSS:      1 << 20,
+				Threads:  3,
+				Children: 1,
+			},
+		},
+		{
+			Lines: 1,
+		},
+	}
+
+	want := `# HELP oneterminal_command_up Whether the command's process is running.
+# TYPE oneterminal_command_up gauge
+oneterminal_command_up{config="dev\\env",command="api \"v2\""} 1
+oneterminal_command_up{config="dev\\env",command="(unnamed 1)"} 0
+# HELP oneterminal_command_ready Whether the command is ready, so its dependents can start.
+# TYPE oneterminal_command_ready gauge
+oneterminal_command_ready{config="dev\\env",command="api \"v2\""} 1
+oneterminal_command_ready{config="dev\\env",command="(unnamed 1)"} 0
+# HELP oneterminal_command_restarts_total Times the command was restarted or reloaded.
+# TYPE oneterminal_command_restarts_total counter
+oneterminal_command_restarts_total{config="dev\\env",command="api \"v2\""} 2
+oneterminal_command_restarts_total{config="dev\\env",command="(unnamed 1)"} 0
+# HELP oneterminal_command_output_lines_total Lines of output printed by the command, including silenced output.
+# TYPE oneterminal_command_output_lines_total counter
+oneterminal_command_output_lines_total{config="dev\\env",command="api \"v2\""} 42
+oneterminal_command_output_lines_total{config="dev\\env",command="(unnamed 1)"} 1
+# HELP oneterminal_command_cpu_seconds CPU time used by the command's running processes and the children they waited for, which drops as processes exit.
+# TYPE oneterminal_command_cpu_seconds gauge
+oneterminal_command_cpu_seconds{config="dev\\env",command="api \"v2\""} 1.5
+# HELP oneterminal_command_memory_rss_bytes Resident memory of the command's processes.
+# TYPE oneterminal_command_memory_rss_bytes gauge
+oneterminal_command_memory_rss_bytes{config="dev\\env",command="api \"v2\""} 1048576
+# HELP oneterminal_command_threads Threads of the command's processes.
+# TYPE oneterminal_command_threads gauge
+oneterminal_command_threads{config="dev\\env",command="api \"v2\""} 3
+# HELP oneterminal_command_children Processes in the command's process group other than its own.
+# TYPE oneterminal_command_children gauge
+oneterminal_command_children{config="dev\\env",command="api \"v2\""} 1
+# HELP oneterminal_command_exits_total Times the command's process exited, by exit code, 128+n for signal n.
+# TYPE oneterminal_command_exits_total counter
+oneterminal_command_exits_total{config="dev\\env",command="api \"v2\"",code="0"} 3
+oneterminal_command_exits_total{config="dev\\env",command="api \"v2\"",code="1"} 2
+oneterminal_command_exits_total{config="dev\\env",command="api \"v2\"",code="130"} 1
+`
+
+	var buf bytes.Buffer
+	writeMetrics(&buf, `dev\env`, statuses)
+	if got := buf.String(); got != want {
+		t.Errorf("want metrics\n%s\ngot\n%s", want, got)
+	}
+}
+
+func TestLabelValue(t *testing.T) {
+	tests := []struct {
+		in, want string
+	}{
+		{"api", "api"},
+		{`say "hi"`, `say \"hi\"`},
+		{`C:\dev`, `C:\\dev`},
+		{"two\nlines", `two\nlines`},
+	}
+	for _, tt := range tests {
+		if got := labelValue(tt.in); got != tt.want {
+			t.Errorf("labelValue(%q): want %q, got %q", tt.in, tt.want, got)
+		}
+	}
+}
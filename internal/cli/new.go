@@ -0,0 +1,216 @@
+package cli
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/alexchao26/oneterminal/internal/yaml"
+	"github.com/spf13/cobra"
+)
+
+func makeNewCmd(allConfigs []yaml.OneTerminalConfig) *cobra.Command {
+	var output string
+
+	newCmd := &cobra.Command{
+		Use:   "new",
+		Short: "Create a config by answering prompts",
+		Long: `Creates a config by prompting for its name, alias, shell and each command's
+fields. Answers are validated as they are entered, and the config is written
+to <config dir>/<name>.yml unless --output is set. Existing files are only
+overwritten after confirming.`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, _ []string) error {
+			p := newPrompter(cmd.InOrStdin(), cmd.OutOrStdout())
+			fmt.Fprintln(p.out, "Creating a new config, press Ctrl+C to cancel")
+
+			config, err := promptConfig(p, allConfigs)
+			if err != nil {
+				return err
+			}
+			contents, err := yaml.Marshal(config)
+			if err != nil {
+				return fmt.Errorf("marshalling config: %w", err)
+			}
+
+			filename := output
+			if filename == "" {
+				filename, err = yaml.NewConfigPath(config.Name + ".yml")
+				if err != nil {
+					return err
+				}
+			}
+			if _, err := os.Stat(filename); err == nil {
+				overwrite, err := p.confirm(fmt.Sprintf("%s already exists, overwrite it?", filename), false)
+				if err != nil {
+					return err
+				}
+				if !overwrite {
+					return fmt.Errorf("not overwriting %s", filename)
+				}
+			}
+
+			if err := writeFile(filename, contents, 0644, true); err != nil {
+				return err
+			}
+			fmt.Fprintf(p.out, "\nConfig written to %s, run it with \"oneterminal %s\"\n", filename, config.Name)
+			return nil
+		},
+	}
+
+	newCmd.Flags().StringVarP(&output, "output", "o", "", "file to write the config to (default: <config dir>/<name>.yml)")
+
+	return newCmd
+}
+
+// promptConfig prompts for the fields of a new config
+func promptConfig(p *prompter, allConfigs []yaml.OneTerminalConfig) (yaml.OneTerminalConfig, error) {
+	var config yaml.OneTerminalConfig
+	var err error
+
+	config.Name, err = p.ask("Name", "", func(name string) error {
+		return yaml.CheckName(name, allConfigs)
+	})
+	if err != nil {
+		return config, err
+	}
+
+	config.Alias, err = p.ask("Alias (optional)", "", func(alias string) error {
+		if alias == "" {
+			return nil
+		}
+		if alias == config.Name {
+			return fmt.Errorf("alias cannot be the same as the name")
+		}
+		return yaml.CheckName(alias, allConfigs)
+	})
+	if err != nil {
+		return config, err
+	}
+
+	config.Shell, err = p.ask("Shell", "zsh", func(shell string) error {
+		return yaml.CheckCommand(yaml.Command{Shell: shell})
+	})
+	if err != nil {
+		return config, err
+	}
+	if config.Shell == "zsh" {
+		// zsh is the default, so leave it out of the yaml
+		config.Shell = ""
+	}
+
+	config.Short, err = p.ask("Short description (optional)", "", nil)
+	if err != nil {
+		return config, err
+	}
+
+	for {
+		fmt.Fprintf(p.out, "\nCommand %d\n", len(config.Commands)+1)
+		cmd, err := promptCommand(p, config.Commands)
+		if err != nil {
+			return config, err
+		}
+		config.Commands = append(config.Commands, cmd)
+
+		another, err := p.confirm("Add another command?", false)
+		if err != nil {
+			return config, err
+		}
+		if !another {
+			return config, nil
+		}
+	}
+}
+
+// promptCommand prompts for the fields of a command. depends-on may only list
+// earlier commands, so the commands cannot form a dependency cycle
+func promptCommand(p *prompter, earlier []yaml.Command) (yaml.Command, error) {
+	var cmd yaml.Command
+	var err error
+
+	earlierNames := make(map[string]bool, len(earlier))
+	for _, c := range earlier {
+		earlierNames[c.Name] = true
+	}
+
+	cmd.Name, err = p.ask("  Name", "", func(name string) error {
+		if name == "" {
+			return fmt.Errorf("name cannot be empty")
+		}
+		if strings.ContainsAny(name, ", \t") {
+			return fmt.Errorf("name cannot contain commas or whitespace")
+		}
+		if earlierNames[name] {
+			return fmt.Errorf("command %q already exists", name)
+		}
+		return nil
+	})
+	if err != nil {
+		return cmd, err
+	}
+
+	cmd.Command, err = p.ask("  Command", "", func(command string) error {
+		if command == "" {
+			return fmt.Errorf("command cannot be empty")
+		}
+		return nil
+	})
+	if err != nil {
+		return cmd, err
+	}
+
+	cmd.CmdDir, err = p.ask("  Directory (optional)", "", func(dir string) error {
+		return yaml.CheckCommand(yaml.Command{CmdDir: dir})
+	})
+	if err != nil {
+		return cmd, err
+	}
+	// relative directories are resolved from the config file, which is not
+	// where the user is answering from
+	if cmd.CmdDir != "" && !filepath.IsAbs(cmd.CmdDir) && !strings.HasPrefix(cmd.CmdDir, "~") &&
+		!strings.HasPrefix(cmd.CmdDir, "$") {
+		if cmd.CmdDir, err = filepath.Abs(cmd.CmdDir); err != nil {
+			return cmd, err
+		}
+	}
+
+	cmd.ReadyRegexp, err = p.ask("  Ready regexp, dependents start once the output matches it (optional)", "",
+		func(pattern string) error {
+			return yaml.CheckCommand(yaml.Command{ReadyRegexp: pattern})
+		})
+	if err != nil {
+		return cmd, err
+	}
+
+	if len(earlier) > 0 {
+		dependsOn, err := p.ask("  Depends on, comma separated command names (optional)", "", func(answer string) error {
+			for _, name := range splitList(answer) {
+				if !earlierNames[name] {
+					return fmt.Errorf("command %q does not exist", name)
+				}
+			}
+			return nil
+		})
+		if err != nil {
+			return cmd, err
+		}
+		for _, name := range splitList(dependsOn) {
+			cmd.DependsOn = append(cmd.DependsOn, yaml.Dependency{Name: name})
+		}
+	}
+
+	cmd.Silence, err = p.confirm("  Silence output?", false)
+	return cmd, err
+}
+
+// splitList splits a comma separated list, dropping empty entries
+func splitList(s string) []string {
+	var list []string
+	for _, item := range strings.Split(s, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			list = append(list, item)
+		}
+	}
+	return list
+}
@@ -0,0 +1,88 @@
+package cli
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/alexchao26/oneterminal/internal/yaml"
+)
+
+func TestPromptConfig(t *testing.T) {
+	dir, err := ioutil.TempDir("", "oneterminal-new")
+	if err != nil {
+		t.Fatalf("malformed test, failed TempDir, err: %s", err)
+	}
+	defer os.RemoveAll(dir)
+
+	existing := []yaml.OneTerminalConfig{{Name: "taken", Path: "/configs/taken.yml"}}
+	answers := []string{
+		// config
+		"", "my app", "list", "taken", "app", // name
+		"app", "", // alias
+		"bash -l", "bash", // shell
+		"", // short description
+		// first command
+		"", "a,b", "web", // name
+		"", "npm start", // command
+		dir + "/missing", dir, // directory
+		"(", "listening", // ready regexp
+		"",           // silence
+		"maybe", "y", // another command
+		// second command
+		"web", "worker", // name
+		"./worker",   // command
+		"",           // directory
+		"",           // ready regexp
+		"api", "web", // depends on
+		"yes", // silence
+		"",    // another command
+	}
+	var out bytes.Buffer
+	p := newPrompter(strings.NewReader(strings.Join(answers, "\n")+"\n"), &out)
+
+	got, err := promptConfig(p, existing)
+	if err != nil {
+		t.Fatalf("want nil error, got %s", err)
+	}
+	want := yaml.OneTerminalConfig{
+		Name:  "app",
+		Shell: "bash",
+		Commands: []yaml.Command{
+			{Name: "web", Command: "npm start", CmdDir: dir, ReadyRegexp: "listening"},
+			{Name: "worker", Command: "./worker", DependsOn: []yaml.Dependency{{Name: "web"}}, Silence: true},
+		},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("want config %+v, got %+v", want, got)
+	}
+
+	// every invalid answer is reported before asking again
+	for wantPart, wantCount := range map[string]int{
+		"  name cannot be empty\n":                             2, // config and command
+		"  name \"my app\" cannot contain whitespace\n":        1,
+		"  \"list\" is the name of a oneterminal command\n":    1,
+		"  \"taken\" is already used by /configs/taken.yml\n":  1,
+		"  alias cannot be the same as the name\n":             1,
+		"  shell \"bash -l\" contains whitespace":              1,
+		"  name cannot contain commas or whitespace\n":         1,
+		"  command cannot be empty\n":                          1,
+		"  directory \"" + dir + "/missing\" does not exist\n": 1,
+		"  invalid regexp \"(\"":                               1,
+		"  answer y or n\n":                                    1,
+		"  command \"web\" already exists\n":                   1,
+		"  command \"api\" does not exist\n":                   1,
+	} {
+		if got := strings.Count(out.String(), wantPart); got != wantCount {
+			t.Errorf("want output to contain %q %d times, got %d times in:\n%s", wantPart, wantCount, got, out.String())
+		}
+	}
+	// running out of answers stops prompting
+	p = newPrompter(strings.NewReader("app\n"), &out)
+	if _, err := promptConfig(p, existing); err == nil || !strings.Contains(err.Error(), "reading answer") {
+		t.Errorf("want error reading answer, got %v", err)
+	}
+}
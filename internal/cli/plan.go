@@ -0,0 +1,254 @@
+package cli
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"github.com/alexchao26/oneterminal/cmdsync"
+	"github.com/alexchao26/oneterminal/internal/yaml"
+)
+
+// secretWords are the words of param and environment variable names whose
+// values are masked when printing a plan, e.g. GITHUB_TOKEN or dbPassword
+var secretWords = map[string]bool{
+	"secret": true, "secrets": true,
+	"token": true, "tokens": true,
+	"password": true, "passwd": true,
+	"credential": true, "credentials": true,
+	"auth": true, "apikey": true, "privatekey": true,
+}
+
+const secretMask = "********"
+
+// printPlan writes the execution plan of a config, with its profile applied,
+// without running anything: each command's fully rendered fields grouped into
+// the dependency waves that they would start in. params holds the values of the
+// params that were passed, others use their defaults. The values of secret
+// params and environment variables are masked where they are substituted.
+func printPlan(w io.Writer, config yaml.OneTerminalConfig, profile string, params map[string]string) error {
+	config, err := config.RenderMasked(params, isSecretName, secretMask)
+	if err != nil {
+		return err
+	}
+	waves, err := config.DependencyWaves()
+	if err != nil {
+		return err
+	}
+	writePlan(w, config, profile, waves)
+	return nil
+}
+
+// writePlan writes the plan of a config's dependency waves
+func writePlan(w io.Writer, config yaml.OneTerminalConfig, profile string, waves [][]yaml.Command) {
+	fmt.Fprintf(w, "Plan for %q", config.Name)
+	if profile != "" {
+		fmt.Fprintf(w, " with profile %q", profile)
+	}
+	fmt.Fprintln(w)
+	fmt.Fprintf(w, "shell: %s\n", formatShell(config.CommandShell(yaml.Command{})))
+	// hooks of included configs run first and clean up last
+	var before, after []string
+	for _, hooks := range config.HookConfigs() {
+		before = append(before, hooks.Before...)
+		after = append(append([]string{}, hooks.After...), after...)
+	}
+	if len(before) > 0 {
+		fmt.Fprintf(w, "before: %s\n", indentLines(strings.Join(before, "\n"), "        "))
+	}
+	if len(after) > 0 {
+		fmt.Fprintf(w, "after: %s\n", indentLines(strings.Join(after, "\n"), "       "))
+	}
+
+	for i, wave := range waves {
+		fmt.Fprintf(w, "\nWave %d\n", i+1)
+		for _, cmd := range wave {
+			name := cmd.Name
+			if name == "" {
+				name = "(unnamed)"
+			}
+			if len(cmd.DependsOn) > 0 {
+				name += fmt.Sprintf(" (after %s)", formatDependencies(cmd.DependsOn))
+			}
+			fmt.Fprintf(w, "  %s\n", name)
+			if len(cmd.Argv) > 0 {
+				fmt.Fprintf(w, "    argv:        %s\n", formatArgv(cmd.Argv[0], cmd.Argv[1:]))
+			} else {
+				fmt.Fprintf(w, "    command:     %s\n", cmd.Command)
+			}
+			if cmd.Shell != "" || len(cmd.ShellArgs) > 0 {
+				fmt.Fprintf(w, "    shell:       %s\n", formatShell(config.CommandShell(cmd)))
+			}
+			if cmd.CmdDir != "" {
+				fmt.Fprintf(w, "    directory:   %s\n", cmdsync.ExpandDir(cmd.CmdDir))
+			}
+			if len(cmd.Environment) > 0 {
+				fmt.Fprintf(w, "    environment: %s\n", formatEnvironment(cmd.Environment))
+			}
+			fmt.Fprintf(w, "    ready:       %s\n", readyCondition(cmd))
+			if cmd.Watch != nil {
+				fmt.Fprintf(w, "    watch:       %s\n", describeWatch(*cmd.Watch))
+			}
+			if cmd.Limits != nil {
+				fmt.Fprintf(w, "    limits:      %s\n", describeLimits(*cmd.Limits))
+			}
+			if len(cmd.Before) > 0 {
+				fmt.Fprintf(w, "    before:      %s\n", indentLines(strings.Join(cmd.Before, "\n"), "                 "))
+			}
+			if len(cmd.After) > 0 {
+				fmt.Fprintf(w, "    after:       %s\n", indentLines(strings.Join(cmd.After, "\n"), "                 "))
+			}
+			if cmd.Silence {
+				fmt.Fprintf(w, "    silenced:    true\n")
+			}
+		}
+	}
+}
+
+// isSecretName reports if a param or environment variable name contains one of
+// the secretWords. Names are split into words at "_", "-" and "." and where
+// camelCase starts a new word, so AUTH_TOKEN is a secret but AUTHOR is not.
+func isSecretName(name string) bool {
+	var words []string
+	var word []rune
+	runes := []rune(name)
+	for i, r := range runes {
+		if r == '_' || r == '-' || r == '.' || (len(word) > 0 && unicode.IsUpper(r) && !unicode.IsUpper(runes[i-1])) {
+			words, word = append(words, string(word)), nil
+		}
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			word = append(word, unicode.ToLower(r))
+		}
+	}
+	words = append(words, string(word))
+
+	for i, w := range words {
+		if secretWords[w] || (i > 0 && secretWords[words[i-1]+w]) {
+			return true
+		}
+	}
+	return false
+}
+
+// formatEnvironment sorts and joins environment variables, masking the values
+// of any that look like secrets
+func formatEnvironment(env map[string]string) string {
+	var keys []string
+	for k := range env {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	var pairs []string
+	for _, k := range keys {
+		v := env[k]
+		if isSecretName(k) {
+			v = secretMask
+		}
+		pairs = append(pairs, fmt.Sprintf("%s=%s", k, v))
+	}
+	return strings.Join(pairs, " ")
+}
+
+// formatDependencies joins the names of dependencies, followed by their
+// conditions unless they wait for the dependency to be ready
+func formatDependencies(deps []yaml.Dependency) string {
+	var parts []string
+	for _, dep := range deps {
+		if dep.Condition == "" || dep.Condition == "ready" {
+			parts = append(parts, dep.Name)
+		} else {
+			parts = append(parts, fmt.Sprintf("%s (%s)", dep.Name, dep.Condition))
+		}
+	}
+	return strings.Join(parts, ", ")
+}
+
+// formatShell describes a shell, leaving out the default -c argument
+func formatShell(shell string, args []string) string {
+	if len(args) == 1 && args[0] == "-c" {
+		return shell
+	}
+	return formatArgv(shell, args)
+}
+
+// formatArgv joins a program and its arguments, quoting arguments that are
+// empty or contain whitespace or quotes
+func formatArgv(program string, args []string) string {
+	parts := []string{program}
+	for _, arg := range args {
+		if arg == "" || strings.ContainsAny(arg, " \t\n'\"") {
+			arg = strconv.Quote(arg)
+		}
+		parts = append(parts, arg)
+	}
+	return strings.Join(parts, " ")
+}
+
+// readyCondition describes when a command's dependents can start
+func readyCondition(cmd yaml.Command) string {
+	var conditions []string
+	if cmd.ReadyRegexp != "" {
+		conditions = append(conditions, fmt.Sprintf("output matches /%s/", cmd.ReadyRegexp))
+	}
+	if cmd.ReadyCheck != "" {
+		conditions = append(conditions, fmt.Sprintf("`%s` succeeds", cmd.ReadyCheck))
+	}
+	conditions = append(conditions, "exits successfully")
+	return strings.Join(conditions, " or ")
+}
+
+// describeLimits lists the limits that are set
+func describeLimits(limits yaml.Limits) string {
+	var parts []string
+	if limits.Nice != 0 {
+		parts = append(parts, fmt.Sprintf("nice %d", limits.Nice))
+	}
+	if limits.NoFile > 0 {
+		parts = append(parts, fmt.Sprintf("nofile %d", limits.NoFile))
+	}
+	if limits.AddressSpace != "" {
+		parts = append(parts, "address-space "+limits.AddressSpace)
+	}
+	if limits.CPUTime != "" {
+		parts = append(parts, "cpu-time "+limits.CPUTime)
+	}
+	if limits.Memory != "" {
+		parts = append(parts, "memory "+limits.Memory)
+	}
+	if limits.CPUs > 0 {
+		parts = append(parts, fmt.Sprintf("cpus %g", limits.CPUs))
+	}
+	if len(parts) == 0 {
+		return "none"
+	}
+	return strings.Join(parts, ", ")
+}
+
+// describeWatch describes the files that restart a command
+func describeWatch(watch yaml.Watch) string {
+	paths := "the command's directory"
+	if len(watch.Paths) > 0 {
+		paths = strings.Join(watch.Paths, ", ")
+	}
+	var details []string
+	if len(watch.Include) > 0 {
+		details = append(details, "include "+strings.Join(watch.Include, " "))
+	}
+	if len(watch.Exclude) > 0 {
+		details = append(details, "exclude "+strings.Join(watch.Exclude, " "))
+	}
+	if watch.Debounce != "" {
+		details = append(details, "debounce "+watch.Debounce)
+	}
+	if watch.RestartDependents {
+		details = append(details, "restarts dependents")
+	}
+	if len(details) == 0 {
+		return paths
+	}
+	return fmt.Sprintf("%s (%s)", paths, strings.Join(details, "; "))
+}
@@ -0,0 +1,98 @@
+package cli
+
+import (
+	"bytes"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/alexchao26/oneterminal/internal/yaml"
+)
+
+func TestPrintPlan_MasksSecrets(t *testing.T) {
+	if old, ok := os.LookupEnv("ONETERMINAL_TEST_TOKEN"); ok {
+		defer os.Setenv("ONETERMINAL_TEST_TOKEN", old)
+	} else {
+		defer os.Unsetenv("ONETERMINAL_TEST_TOKEN")
+	}
+	os.Setenv("ONETERMINAL_TEST_TOKEN", "ghp_abc123")
+
+	config := yaml.OneTerminalConfig{
+		Name: "app",
+		Params: []yaml.Param{
+			{Name: "apiToken", Default: "default-token"},
+			{Name: "dbPassword", Default: "default-password"},
+			{Name: "authEnabled", Default: "true"},
+			{Name: "port", Default: "8080"},
+		},
+		Before: []string{"login --token {{ .Params.apiToken }}"},
+		Commands: []yaml.Command{
+			{
+				Name:    "api",
+				Command: "serve --port {{ .Params.port }} --token {{ .Params.apiToken }} --auth={{ .Params.authEnabled }} --tls=true",
+				Environment: map[string]string{
+					"AUTH_ENABLED": "true",
+					"AUTHOR":       "potato",
+					"DB_PASSWORD":  "{{ .Params.dbPassword }}",
+					"LOG_LEVEL":    "debug",
+					"OAUTH_PORT":   "8080",
+				},
+			},
+			{
+				Name:      "worker",
+				Command:   `work --github {{ env "ONETERMINAL_TEST_TOKEN" }} --port 8080`,
+				DependsOn: []yaml.Dependency{{Name: "api"}},
+			},
+		},
+	}
+
+	var out bytes.Buffer
+	if err := printPlan(&out, config, "", map[string]string{"apiToken": "passed-token"}); err != nil {
+		t.Fatalf("want nil error, got %s", err)
+	}
+	plan := out.String()
+
+	for _, secret := range []string{"passed-token", "default-password", "ghp_abc123"} {
+		if strings.Contains(plan, secret) {
+			t.Errorf("want %q masked, got plan:\n%s", secret, plan)
+		}
+	}
+	for _, want := range []string{
+		"before: login --token ********",
+		// values of secrets are only masked where they are substituted
+		"command:     serve --port 8080 --token ******** --auth=******** --tls=true",
+		"environment: AUTHOR=potato AUTH_ENABLED=******** DB_PASSWORD=******** LOG_LEVEL=debug OAUTH_PORT=8080",
+		"command:     work --github ******** --port 8080",
+	} {
+		if !strings.Contains(plan, want) {
+			t.Errorf("want plan to contain %q, got:\n%s", want, plan)
+		}
+	}
+}
+
+func TestIsSecretName(t *testing.T) {
+	tests := []struct {
+		name string
+		want bool
+	}{
+		{"GITHUB_TOKEN", true},
+		{"dbPassword", true},
+		{"AUTH", true},
+		{"AUTH_ENABLED", true},
+		{"api-key", true},
+		{"APIKey", true},
+		{"PRIVATE_KEY", true},
+		{"client.secret", true},
+		{"AUTHOR", false},
+		{"AUTHORITY", false},
+		{"OAUTH_PORT", false},
+		{"tokenizer", false},
+		{"KEYBOARD", false},
+		{"port", false},
+	}
+	for _, tt := range tests {
+		if got := isSecretName(tt.name); got != tt.want {
+			t.Errorf("isSecretName(%q): want %v, got %v", tt.name, tt.want, got)
+		}
+	}
+}
@@ -0,0 +1,81 @@
+package cli
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// prompter asks questions on out and reads the answers from in
+type prompter struct {
+	in  *bufio.Reader
+	out io.Writer
+}
+
+func newPrompter(in io.Reader, out io.Writer) *prompter {
+	return &prompter{in: bufio.NewReader(in), out: out}
+}
+
+// ask prompts until an answer passes check, which may be nil. An empty answer
+// is replaced by defaultValue
+func (p *prompter) ask(question, defaultValue string, check func(string) error) (string, error) {
+	prompt := question + ": "
+	if defaultValue != "" {
+		prompt = fmt.Sprintf("%s [%s]: ", question, defaultValue)
+	}
+
+	for {
+		answer, err := p.readLine(prompt)
+		if err != nil {
+			return "", err
+		}
+		if answer == "" {
+			answer = defaultValue
+		}
+
+		if check != nil {
+			if err := check(answer); err != nil {
+				fmt.Fprintf(p.out, "  %s\n", err)
+				continue
+			}
+		}
+		return answer, nil
+	}
+}
+
+// confirm asks a yes or no question
+func (p *prompter) confirm(question string, defaultYes bool) (bool, error) {
+	prompt := question + " [y/N]: "
+	if defaultYes {
+		prompt = question + " [Y/n]: "
+	}
+
+	for {
+		answer, err := p.readLine(prompt)
+		if err != nil {
+			return false, err
+		}
+		switch strings.ToLower(answer) {
+		case "":
+			return defaultYes, nil
+		case "y", "yes":
+			return true, nil
+		case "n", "no":
+			return false, nil
+		}
+		fmt.Fprintln(p.out, "  answer y or n")
+	}
+}
+
+// readLine prints prompt and returns the next line of input without
+// surrounding whitespace
+func (p *prompter) readLine(prompt string) (string, error) {
+	fmt.Fprint(p.out, prompt)
+	line, err := p.in.ReadString('\n')
+	if err != nil && (err != io.EOF || line == "") {
+		fmt.Fprintln(p.out)
+		return "", fmt.Errorf("reading answer: %w", err)
+	}
+	return strings.TrimSpace(line), nil
+}
@@ -0,0 +1,182 @@
+package cli
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/alexchao26/oneterminal/cmdsync"
+	"github.com/alexchao26/oneterminal/internal/yaml"
+	"github.com/spf13/cobra"
+)
+
+// reloadInterval is how often the files of a running config are checked for
+// changes
+const reloadInterval = time.Second
+
+// reloader restarts the commands of a running Group whose definitions change
+// when the files of its config are edited
+type reloader struct {
+	cobraCommand *cobra.Command
+	flags        *runFlags
+	group        *cmdsync.Group
+	config       yaml.OneTerminalConfig // the resolved config that is running
+	modTimes     map[string]time.Time   // of the config's files
+}
+
+func newReloader(cobraCommand *cobra.Command, flags *runFlags, group *cmdsync.Group,
+	config yaml.OneTerminalConfig) *reloader {
+	r := &reloader{
+		cobraCommand: cobraCommand,
+		flags:        flags,
+		group:        group,
+	}
+	r.setConfig(config)
+	return r
+}
+
+// watch checks the config's files every reloadInterval and reloads the config
+// when any of them changed, until ctx is done
+func (r *reloader) watch(ctx context.Context) {
+	ticker := time.NewTicker(reloadInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		changed := false
+		for filename, modTime := range r.modTimes {
+			if info, err := os.Stat(filename); err == nil && !info.ModTime().Equal(modTime) {
+				changed = true
+			}
+		}
+		if !changed {
+			continue
+		}
+		if err := r.reload(); err != nil {
+			fmt.Printf("reloading %q: %v, still running the previous config\n", r.config.Name, err)
+		}
+	}
+}
+
+// reload parses the config again and applies the differences to the Group:
+// changed commands are restarted, added commands started and removed
+// commands stopped
+func (r *reloader) reload() error {
+	// the files are only reloaded again once they change again, even if this
+	// version of the config is broken
+	r.updateModTimes()
+
+	allConfigs, warnings, err := yaml.ParseAllConfigs()
+	if err != nil {
+		return err
+	}
+	var config yaml.OneTerminalConfig
+	found := false
+	for _, c := range allConfigs {
+		if c.Name == r.config.Name {
+			config, found = c, true
+		}
+	}
+	if !found {
+		for _, warning := range warnings {
+			if strings.Contains(warning.Error(), r.config.Path) {
+				return warning
+			}
+		}
+		return fmt.Errorf("config no longer exists")
+	}
+
+	resolved, err := r.flags.resolve(r.cobraCommand, config)
+	if err != nil {
+		return err
+	}
+	if _, err := resolved.DependencyWaves(); err != nil {
+		return err
+	}
+	for _, c := range []yaml.OneTerminalConfig{r.config, resolved} {
+		for _, cmd := range c.Commands {
+			if cmd.Name == "" {
+				return fmt.Errorf("commands need names to be reloaded")
+			}
+		}
+	}
+
+	changes := yaml.DiffCommands(r.config, resolved)
+	if changes.IsEmpty() {
+		r.setConfig(resolved)
+		return nil
+	}
+
+	restart := make(map[string]bool)
+	for _, name := range changes.Added {
+		restart[name] = true
+	}
+	for _, name := range changes.Changed {
+		restart[name] = true
+	}
+	var shellCmds []*cmdsync.ShellCmd
+	for i, cmd := range resolved.Commands {
+		if !restart[cmd.Name] {
+			continue
+		}
+		s, err := makeShellCmd(resolved, i, cmd)
+		if err != nil {
+			return err
+		}
+		shellCmds = append(shellCmds, s)
+	}
+
+	fmt.Printf("reloading %q%s\n", resolved.Name, describeChanges(changes))
+	for _, name := range changes.Removed {
+		if err := r.group.Remove(name); err != nil {
+			return err
+		}
+	}
+	for _, s := range shellCmds {
+		if err := r.group.Replace(s); err != nil {
+			return err
+		}
+	}
+
+	r.setConfig(resolved)
+	return nil
+}
+
+func (r *reloader) setConfig(config yaml.OneTerminalConfig) {
+	r.config = config
+	r.modTimes = map[string]time.Time{config.Path: {}}
+	for _, cmd := range config.Commands {
+		// included commands are declared in other files
+		r.modTimes[cmd.Source()] = time.Time{}
+	}
+	r.updateModTimes()
+}
+
+func (r *reloader) updateModTimes() {
+	for filename := range r.modTimes {
+		if info, err := os.Stat(filename); err == nil {
+			r.modTimes[filename] = info.ModTime()
+		}
+	}
+}
+
+// describeChanges lists the commands that are restarted, started and stopped
+func describeChanges(changes yaml.CommandChanges) string {
+	var parts []string
+	if len(changes.Changed) > 0 {
+		parts = append(parts, "restarting "+strings.Join(changes.Changed, ", "))
+	}
+	if len(changes.Added) > 0 {
+		parts = append(parts, "starting "+strings.Join(changes.Added, ", "))
+	}
+	if len(changes.Removed) > 0 {
+		parts = append(parts, "stopping "+strings.Join(changes.Removed, ", "))
+	}
+	return ": " + strings.Join(parts, "; ")
+}
@@ -0,0 +1,216 @@
+package cli
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"syscall"
+	"time"
+
+	"github.com/alexchao26/oneterminal/cmdsync"
+	"github.com/alexchao26/oneterminal/internal/yaml"
+)
+
+// usageInterval is how often a running config records the resource usage of
+// its commands in its state file
+const usageInterval = 2 * time.Second
+
+// supervisorState is written to a state file while a config is running, so
+// other oneterminal processes can tell which configs are running and how many
+// resources their commands use
+type supervisorState struct {
+	Config   string         `json:"config"`
+	Path     string         `json:"path"` // of the config's file
+	PID      int            `json:"pid"`
+	Started  time.Time      `json:"started"`
+	Sampled  time.Time      `json:"sampled"` // when Commands was last recorded
+	Commands []commandUsage `json:"commands"`
+}
+
+// commandUsage is the resource usage of a running command's process group
+type commandUsage struct {
+	Name       string  `json:"name"`
+	CPUPercent float64 `json:"cpu_percent"`
+	RSS        uint64  `json:"rss_bytes"`
+	Threads    int     `json:"threads"`
+	Children   int     `json:"children"`
+}
+
+// stateDir returns the directory of supervisor state files, which is cleared on
+// logout if $XDG_RUNTIME_DIR is set
+func stateDir() string {
+	if runtimeDir := os.Getenv("XDG_RUNTIME_DIR"); runtimeDir != "" {
+		return filepath.Join(runtimeDir, "oneterminal")
+	}
+	return filepath.Join(os.TempDir(), fmt.Sprintf("oneterminal-%d", os.Getuid()))
+}
+
+// stateFile returns the state file of a config, named after the config and a
+// hash of its file's path, so that configs with the same name in different
+// projects do not share a state file
+func stateFile(configName, configPath string) string {
+	if abs, err := filepath.Abs(configPath); err == nil {
+		configPath = abs
+	}
+	sum := sha256.Sum256([]byte(configPath))
+	return filepath.Join(stateDir(), fmt.Sprintf("%s-%x.json", configName, sum[:8]))
+}
+
+func (state supervisorState) file() string {
+	return stateFile(state.Config, state.Path)
+}
+
+// markRunning writes the state file of a config that this process is about to
+// run, and records the usage of group's commands in it until the returned
+// function is called, which removes it again
+func markRunning(config yaml.OneTerminalConfig, group *cmdsync.Group) (func(), error) {
+	if err := os.MkdirAll(stateDir(), 0700); err != nil {
+		return nil, fmt.Errorf("making state directory: %w", err)
+	}
+	state := supervisorState{Config: config.Name, Path: config.Path, PID: os.Getpid(), Started: time.Now()}
+	if err := lockState(state, func() error { return writeState(state) }); err != nil {
+		return nil, fmt.Errorf("writing state file: %w", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	recorded := make(chan struct{})
+	go func() {
+		defer close(recorded)
+		recordUsage(ctx, state, group)
+	}()
+
+	return func() {
+		cancel()
+		<-recorded
+		removeState(state)
+	}, nil
+}
+
+// recordUsage samples the usage of group's commands into state's file every
+// usageInterval until ctx is done
+func recordUsage(ctx context.Context, state supervisorState, group *cmdsync.Group) {
+	ticker := time.NewTicker(usageInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		state.Sampled = time.Now()
+		state.Commands = nil
+		for _, usage := range group.Usage() {
+			state.Commands = append(state.Commands, commandUsage{
+				Name:       usage.Name,
+				CPUPercent: usage.CPUPercent,
+				RSS:        usage.RSS,
+				Threads:    usage.Threads,
+				Children:   usage.Children,
+			})
+		}
+
+		replaced := false
+		lockState(state, func() error {
+			// another run of the same config may have replaced the file
+			if current, ok := readState(state.file()); !ok || current.PID != state.PID {
+				replaced = true
+				return nil
+			}
+			return writeState(state)
+		})
+		if replaced {
+			return
+		}
+	}
+}
+
+// lockState runs fn while holding an exclusive lock of state's file, so
+// checking which supervisor wrote the file and then replacing or removing it
+// cannot interleave with other oneterminal processes
+func lockState(state supervisorState, fn func() error) error {
+	lock, err := os.OpenFile(state.file()+".lock", os.O_CREATE|os.O_RDWR, 0600)
+	if err != nil {
+		return err
+	}
+	// closing the file releases the lock
+	defer lock.Close()
+	if err := syscall.Flock(int(lock.Fd()), syscall.LOCK_EX); err != nil {
+		return err
+	}
+	return fn()
+}
+
+// removeState removes the state file of a config if it still is the one that
+// state's supervisor wrote
+func removeState(state supervisorState) {
+	lockState(state, func() error {
+		if current, ok := readState(state.file()); ok && current.PID == state.PID {
+			os.Remove(state.file())
+		}
+		return nil
+	})
+}
+
+// writeState replaces the state file of a config, via a rename so readers
+// never see a partially written file
+func writeState(state supervisorState) error {
+	contents, err := json.Marshal(state)
+	if err != nil {
+		return err
+	}
+	filename := state.file()
+	if err := os.WriteFile(filename+".tmp", contents, 0600); err != nil {
+		return err
+	}
+	return os.Rename(filename+".tmp", filename)
+}
+
+// runningStates returns the states of all running configs, including configs
+// that are not in the config directories, e.g. project-local configs
+func runningStates() []supervisorState {
+	filenames, _ := filepath.Glob(filepath.Join(stateDir(), "*.json"))
+	var states []supervisorState
+	for _, filename := range filenames {
+		if state, ok := runningStateOf(filename); ok {
+			states = append(states, state)
+		}
+	}
+	return states
+}
+
+// runningState returns the state of a config's supervisor if it is running
+func runningState(config yaml.OneTerminalConfig) (supervisorState, bool) {
+	return runningStateOf(stateFile(config.Name, config.Path))
+}
+
+// runningStateOf returns the state in a state file if its supervisor is
+// running. State files left behind by supervisors that did not exit cleanly are
+// removed
+func runningStateOf(filename string) (supervisorState, bool) {
+	state, ok := readState(filename)
+	if !ok {
+		return state, false
+	}
+	if err := syscall.Kill(state.PID, 0); errors.Is(err, syscall.ESRCH) {
+		removeState(state)
+		return state, false
+	}
+	return state, true
+}
+
+func readState(filename string) (supervisorState, bool) {
+	var state supervisorState
+	contents, err := os.ReadFile(filename)
+	if err != nil {
+		return state, false
+	}
+	if err := json.Unmarshal(contents, &state); err != nil || state.PID == 0 {
+		return state, false
+	}
+	return state, true
+}
@@ -0,0 +1,124 @@
+package cli
+
+import (
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"testing"
+	"time"
+
+	"github.com/alexchao26/oneterminal/cmdsync"
+	"github.com/alexchao26/oneterminal/internal/yaml"
+)
+
+// useTempStateDir points the state directory at a temporary directory until
+// the returned function is called
+func useTempStateDir(t *testing.T) func() {
+	dir, err := ioutil.TempDir("", "oneterminal-state")
+	if err != nil {
+		t.Fatalf("malformed test, failed TempDir, err: %s", err)
+	}
+	runtimeDir, hadRuntimeDir := os.LookupEnv("XDG_RUNTIME_DIR")
+	os.Setenv("XDG_RUNTIME_DIR", dir)
+	if err := os.MkdirAll(stateDir(), 0700); err != nil {
+		t.Fatalf("malformed test, failed MkdirAll, err: %s", err)
+	}
+	return func() {
+		if hadRuntimeDir {
+			os.Setenv("XDG_RUNTIME_DIR", runtimeDir)
+		} else {
+			os.Unsetenv("XDG_RUNTIME_DIR")
+		}
+		os.RemoveAll(dir)
+	}
+}
+
+func TestRunningState(t *testing.T) {
+	defer useTempStateDir(t)()
+
+	exited := exec.Command("true")
+	if err := exited.Run(); err != nil {
+		t.Fatalf("malformed test, failed to run true, err: %s", err)
+	}
+
+	tests := []struct {
+		name        string
+		contents    string // written as is if set
+		pid         int
+		wantRunning bool
+		wantRemoved bool
+	}{
+		{name: "running supervisor", pid: os.Getpid(), wantRunning: true},
+		{name: "exited supervisor", pid: exited.Process.Pid, wantRemoved: true},
+		{name: "malformed file", contents: "{not json"},
+		{name: "file without pid", contents: `{"config":"no-pid"}`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := yaml.OneTerminalConfig{Name: tt.name, Path: "/configs/app.yml"}
+			if tt.contents != "" {
+				if err := ioutil.WriteFile(stateFile(config.Name, config.Path), []byte(tt.contents), 0600); err != nil {
+					t.Fatalf("malformed test, failed WriteFile, err: %s", err)
+				}
+			} else if err := writeState(supervisorState{Config: tt.name, Path: config.Path, PID: tt.pid}); err != nil {
+				t.Fatalf("malformed test, failed writeState, err: %s", err)
+			}
+
+			state, running := runningState(config)
+			if running != tt.wantRunning {
+				t.Errorf("want running %t, got %t", tt.wantRunning, running)
+			}
+			if running && state.PID != tt.pid {
+				t.Errorf("want state of pid %d, got %+v", tt.pid, state)
+			}
+			_, err := os.Stat(stateFile(config.Name, config.Path))
+			if removed := os.IsNotExist(err); removed != tt.wantRemoved {
+				t.Errorf("want state file removed %t, got %t", tt.wantRemoved, removed)
+			}
+		})
+	}
+
+	states := runningStates()
+	if len(states) != 1 || states[0].Config != "running supervisor" {
+		t.Errorf("want only the running supervisor's state, got %+v", states)
+	}
+}
+
+func TestMarkRunning(t *testing.T) {
+	defer useTempStateDir(t)()
+
+	config := yaml.OneTerminalConfig{Name: "app", Path: "/code/shop/.oneterminal.yml"}
+	release, err := markRunning(config, cmdsync.NewGroup())
+	if err != nil {
+		t.Fatalf("want nil error, got %s", err)
+	}
+	state, running := runningState(config)
+	if !running || state.PID != os.Getpid() || state.Config != "app" || state.Path != config.Path {
+		t.Errorf("want app running in this process, got %+v running %t", state, running)
+	}
+	// a config with the same name in another project is not running
+	if state, running := runningState(yaml.OneTerminalConfig{Name: "app", Path: "/code/blog/.oneterminal.yml"}); running {
+		t.Errorf("want the other project's app not running, got %+v", state)
+	}
+	release()
+	if _, err := os.Stat(stateFile(config.Name, config.Path)); !os.IsNotExist(err) {
+		t.Errorf("want state file removed once released, got %v", err)
+	}
+
+	// another supervisor of the same config takes over the state file, which
+	// is neither overwritten with usage nor removed by the first one
+	release, err = markRunning(config, cmdsync.NewGroup())
+	if err != nil {
+		t.Fatalf("want nil error, got %s", err)
+	}
+	other := supervisorState{Config: "app", Path: config.Path, PID: os.Getppid()}
+	if err := writeState(other); err != nil {
+		t.Fatalf("malformed test, failed writeState, err: %s", err)
+	}
+	time.Sleep(usageInterval + 500*time.Millisecond)
+	release()
+	if state, ok := readState(stateFile(config.Name, config.Path)); !ok || state.PID != other.PID || !state.Sampled.IsZero() {
+		t.Errorf("want the other supervisor's state to be kept, got %+v", state)
+	}
+}
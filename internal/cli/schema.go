@@ -0,0 +1,31 @@
+package cli
+
+import (
+	"fmt"
+
+	"github.com/alexchao26/oneterminal/internal/yaml"
+	"github.com/spf13/cobra"
+)
+
+func makeSchemaCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "schema",
+		Short: "Print the JSON Schema of config files",
+		Long: `Prints the JSON Schema of oneterminal config files, which editors using
+yaml-language-server can use for autocomplete and inline validation.
+
+  oneterminal schema > ~/.config/oneterminal/schema.json
+
+Then add this comment to the top of a config file
+  # yaml-language-server: $schema=./schema.json`,
+		Args: cobra.ExactArgs(0),
+		RunE: func(_ *cobra.Command, _ []string) error {
+			schema, err := yaml.JSONSchema()
+			if err != nil {
+				return fmt.Errorf("generating schema: %w", err)
+			}
+			fmt.Println(string(schema))
+			return nil
+		},
+	}
+}
@@ -0,0 +1,126 @@
+package cli
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"text/tabwriter"
+	"time"
+
+	"github.com/alexchao26/oneterminal/internal/yaml"
+	"github.com/spf13/cobra"
+)
+
+func makeStatusCmd(allConfigs []yaml.OneTerminalConfig) *cobra.Command {
+	configsByName, names := configLookup(allConfigs)
+	var asJSON bool
+
+	statusCmd := &cobra.Command{
+		Use:   "status [name]",
+		Short: "Show running configs and the CPU and memory their commands use",
+		Long: `Shows every running config, or only the given one, with the resource usage
+of each of its running commands. Usage covers a command's whole process group,
+including processes it started, and is sampled from /proc every few seconds
+(Linux only):
+  CPU       CPU used since the previous sample, 100% is one full core
+  MEMORY    resident memory
+  THREADS   threads of all of its processes
+  CHILDREN  processes other than the command's own
+
+A name that is not a config found from here, e.g. a project-local config of
+another project, shows every running config with that name.
+
+--json prints the same information for scripts.`,
+		Args:      cobra.MaximumNArgs(1),
+		ValidArgs: names,
+		RunE: func(_ *cobra.Command, args []string) error {
+			// an empty list rather than null when nothing is running
+			states := []supervisorState{}
+			if len(args) == 0 {
+				states = append(states, runningStates()...)
+			} else {
+				config, known := configsByName[args[0]]
+				for _, state := range runningStates() {
+					// configs that are not found from here, e.g. project-local
+					// configs of other projects, are matched by name
+					if (known && state.Config == config.Name && state.Path == config.Path) ||
+						(!known && state.Config == args[0]) {
+						states = append(states, state)
+					}
+				}
+				if len(states) == 0 {
+					return fmt.Errorf("%q is not running", args[0])
+				}
+			}
+
+			if asJSON {
+				encoder := json.NewEncoder(os.Stdout)
+				encoder.SetIndent("", "  ")
+				return encoder.Encode(states)
+			}
+			if len(states) == 0 {
+				fmt.Println("No configs are running")
+				return nil
+			}
+			for i, state := range states {
+				if i > 0 {
+					fmt.Println()
+				}
+				printStatus(os.Stdout, state)
+			}
+			return nil
+		},
+	}
+
+	statusCmd.Flags().BoolVar(&asJSON, "json", false, "print the running configs as json")
+
+	return statusCmd
+}
+
+// printStatus writes a running config's state and a table of its commands'
+// resource usage
+func printStatus(out io.Writer, state supervisorState) {
+	fmt.Fprintf(out, "%s, running since %s (pid %d)\n",
+		state.Config, state.Started.Format("2006-01-02 15:04:05"), state.PID)
+	if state.Path != "" {
+		fmt.Fprintf(out, "  file: %s\n", state.Path)
+	}
+	if state.Sampled.IsZero() {
+		fmt.Fprintf(out, "  no usage sampled yet\n")
+		return
+	}
+	if len(state.Commands) == 0 {
+		fmt.Fprintf(out, "  no commands are running\n")
+		return
+	}
+
+	w := tabwriter.NewWriter(out, 0, 8, 2, ' ', 0)
+	fmt.Fprintf(w, "  COMMAND\tCPU\tMEMORY\tTHREADS\tCHILDREN\n")
+	for _, cmd := range state.Commands {
+		name := cmd.Name
+		if name == "" {
+			name = "(unnamed)"
+		}
+		fmt.Fprintf(w, "  %s\t%.1f%%\t%s\t%d\t%d\n",
+			name, cmd.CPUPercent, formatBytes(cmd.RSS), cmd.Threads, cmd.Children)
+	}
+	w.Flush()
+	fmt.Fprintf(out, "  sampled %s ago\n", time.Since(state.Sampled).Round(time.Second))
+}
+
+// formatBytes formats a number of bytes with a K, M, G or T suffix, the same
+// powers of 1024 that limits use
+func formatBytes(n uint64) string {
+	if n < 1024 {
+		return fmt.Sprintf("%dB", n)
+	}
+	size := float64(n)
+	for _, unit := range []string{"K", "M", "G", "T"} {
+		size /= 1024
+		if size < 1024 || unit == "T" {
+			return fmt.Sprintf("%.1f%s", size, unit)
+		}
+	}
+	return ""
+}
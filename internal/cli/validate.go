@@ -0,0 +1,43 @@
+package cli
+
+import (
+	"fmt"
+
+	"github.com/alexchao26/oneterminal/internal/yaml"
+	"github.com/spf13/cobra"
+)
+
+func makeValidateCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:     "validate",
+		Aliases: []string{"lint"},
+		Short:   "Check all config files for problems",
+		Long: `Thoroughly checks every config file for problems like unknown yaml keys,
+unsupported shells, invalid regexps, missing directories, dependency cycles
+and duplicate names.
+
+All problems are printed as file:line: message. Exits with a non-zero code if
+any problems are found, so it can be used in CI.`,
+		Args:          cobra.ExactArgs(0),
+		SilenceUsage:  true,
+		SilenceErrors: true,
+		RunE: func(_ *cobra.Command, _ []string) error {
+			problems, err := yaml.Validate()
+			if err != nil {
+				return fmt.Errorf("validating configs: %w", err)
+			}
+
+			files := make(map[string]bool)
+			for _, p := range problems {
+				fmt.Println(p)
+				files[p.File] = true
+			}
+			if len(problems) > 0 {
+				return fmt.Errorf("found %d problem(s) in %d file(s)", len(problems), len(files))
+			}
+
+			fmt.Println("All configs are valid")
+			return nil
+		},
+	}
+}
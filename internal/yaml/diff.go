@@ -0,0 +1,54 @@
+package yaml
+
+import "reflect"
+
+// CommandChanges are the names of the commands that differ between two
+// versions of a config
+type CommandChanges struct {
+	Added   []string
+	Removed []string
+	Changed []string
+}
+
+// IsEmpty returns true if no commands differ
+func (c CommandChanges) IsEmpty() bool {
+	return len(c.Added) == 0 && len(c.Removed) == 0 && len(c.Changed) == 0
+}
+
+// DiffCommands compares the commands of two versions of a config by name. A
+// command has changed if any of its fields differ or if the shell it runs in
+// has changed. Both configs are expected to be resolved the same way, e.g.
+// with the same profile and params.
+func DiffCommands(old, new OneTerminalConfig) CommandChanges {
+	var changes CommandChanges
+
+	oldCommands := make(map[string]Command, len(old.Commands))
+	for _, cmd := range old.Commands {
+		oldCommands[cmd.Name] = cmd
+	}
+	newNames := make(map[string]bool, len(new.Commands))
+	for _, cmd := range new.Commands {
+		newNames[cmd.Name] = true
+		oldCmd, ok := oldCommands[cmd.Name]
+		switch {
+		case !ok:
+			changes.Added = append(changes.Added, cmd.Name)
+		case !reflect.DeepEqual(oldCmd, cmd) || !sameShell(old, new, cmd):
+			changes.Changed = append(changes.Changed, cmd.Name)
+		}
+	}
+	for _, cmd := range old.Commands {
+		if !newNames[cmd.Name] {
+			changes.Removed = append(changes.Removed, cmd.Name)
+		}
+	}
+
+	return changes
+}
+
+// sameShell returns true if cmd runs in the same shell in both configs
+func sameShell(old, new OneTerminalConfig, cmd Command) bool {
+	oldShell, oldArgs := old.CommandShell(cmd)
+	newShell, newArgs := new.CommandShell(cmd)
+	return oldShell == newShell && reflect.DeepEqual(oldArgs, newArgs)
+}
@@ -0,0 +1,71 @@
+package yaml
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestDiffCommands(t *testing.T) {
+	old := OneTerminalConfig{
+		Name: "app",
+		Commands: []Command{
+			{Name: "db", Command: "postgres"},
+			{Name: "api", Command: "go run .", Environment: map[string]string{"PORT": "8080"}},
+			{Name: "ui", Command: "npm start"},
+		},
+	}
+
+	tests := []struct {
+		name   string
+		modify func(c *OneTerminalConfig)
+		want   CommandChanges
+	}{
+		{
+			name:   "no changes",
+			modify: func(c *OneTerminalConfig) {},
+			want:   CommandChanges{},
+		},
+		{
+			name: "changed environment",
+			modify: func(c *OneTerminalConfig) {
+				c.Commands[1].Environment = map[string]string{"PORT": "9090"}
+			},
+			want: CommandChanges{Changed: []string{"api"}},
+		},
+		{
+			name: "added, removed and changed",
+			modify: func(c *OneTerminalConfig) {
+				c.Commands = []Command{
+					{Name: "db", Command: "postgres", DependsOn: []Dependency{{Name: "migrate"}}},
+					{Name: "migrate", Command: "make migrate"},
+					{Name: "api", Command: "go run .", Environment: map[string]string{"PORT": "8080"}},
+				}
+			},
+			want: CommandChanges{Added: []string{"migrate"}, Removed: []string{"ui"}, Changed: []string{"db"}},
+		},
+		{
+			name: "changed shell changes every command",
+			modify: func(c *OneTerminalConfig) {
+				c.Shell = "bash"
+			},
+			want: CommandChanges{Changed: []string{"db", "api", "ui"}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			new := old
+			new.Commands = make([]Command, len(old.Commands))
+			copy(new.Commands, old.Commands)
+			tt.modify(&new)
+
+			got := DiffCommands(old, new)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("want %+v, got %+v", tt.want, got)
+			}
+			if got.IsEmpty() != tt.want.IsEmpty() {
+				t.Errorf("want IsEmpty() %v, got %v", tt.want.IsEmpty(), got.IsEmpty())
+			}
+		})
+	}
+}
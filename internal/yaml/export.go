@@ -0,0 +1,677 @@
+package yaml
+
+import (
+	"bytes"
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ToProcfile converts a rendered config into a Procfile with a process for
+// each command. Procfiles start every process at once, so warnings are
+// returned for the depends-on lists, ready conditions and hooks that are lost,
+// and for memory and cpus limits, which need a cgroup. Characters that
+// Procfiles do not allow in process names are replaced with "-".
+func ToProcfile(config OneTerminalConfig) ([]byte, []string, error) {
+	config, err := nameUnnamed(config)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var buf bytes.Buffer
+	var warnings []string
+	processes := make(map[string]string, len(config.Commands))
+	if len(config.HookConfigs()) > 0 {
+		warnings = append(warnings, "the config's before and after hooks will not run, Procfiles only start processes")
+	}
+
+	for _, cmd := range config.Commands {
+		line, err := commandInvocation(config, cmd)
+		if err != nil {
+			return nil, nil, err
+		}
+		if strings.Contains(line, "\n") {
+			return nil, nil, fmt.Errorf("command %q spans multiple lines, which Procfiles do not support", cmd.Name)
+		}
+		if cmd.Silence {
+			line += " >/dev/null 2>&1"
+		}
+		process := procfileNamePattern.ReplaceAllString(cmd.Name, "-")
+		if other, ok := processes[process]; ok {
+			return nil, nil, fmt.Errorf("commands %q and %q are both named %q in Procfiles", other, cmd.Name, process)
+		}
+		processes[process] = cmd.Name
+		fmt.Fprintf(&buf, "%s: %s\n", process, line)
+
+		if len(cmd.DependsOn) > 0 {
+			warnings = append(warnings, fmt.Sprintf(
+				"%q will not wait for %s, Procfiles start every process at once",
+				cmd.Name, strings.Join(quoteAll(cmd.DependencyNames()), ", ")))
+		}
+		if cmd.Watch != nil {
+			warnings = append(warnings, fmt.Sprintf("%q will not be restarted when its files change", cmd.Name))
+		}
+		if len(cmd.Before) > 0 || len(cmd.After) > 0 {
+			warnings = append(warnings, fmt.Sprintf("the before and after hooks of %q will not run", cmd.Name))
+		}
+		if cmd.Limits != nil && (cmd.Limits.Memory != "" || cmd.Limits.CPUs > 0) {
+			warnings = append(warnings, fmt.Sprintf("the memory and cpus limits of %q are not applied", cmd.Name))
+		}
+	}
+
+	return buf.Bytes(), warnings, nil
+}
+
+// procfileNamePattern matches characters that are not allowed in Procfile
+// process names
+var procfileNamePattern = regexp.MustCompile(`[^a-zA-Z0-9_-]`)
+
+// nameUnnamed names unnamed commands by their position, e.g. "unnamed-2", as
+// exported processes and units need distinct names
+func nameUnnamed(config OneTerminalConfig) (OneTerminalConfig, error) {
+	names := make(map[string]bool, len(config.Commands))
+	for _, cmd := range config.Commands {
+		names[cmd.Name] = true
+	}
+
+	commands := make([]Command, 0, len(config.Commands))
+	for i, cmd := range config.Commands {
+		if cmd.Name == "" {
+			cmd.Name = fmt.Sprintf("unnamed-%d", i)
+			if names[cmd.Name] {
+				return config, fmt.Errorf("unnamed command no. %d cannot be named %q, another command has that name", i, cmd.Name)
+			}
+		}
+		commands = append(commands, cmd)
+	}
+	config.Commands = commands
+	return config, nil
+}
+
+// SystemdUnit is a generated systemd unit file
+type SystemdUnit struct {
+	Name     string
+	Contents []byte
+}
+
+// unitNamePattern matches characters that are not allowed in systemd unit names
+var unitNamePattern = regexp.MustCompile(`[^a-zA-Z0-9:_.\\-]`)
+
+// ToSystemd converts a rendered config into systemd user units: a service for
+// each command and a target that starts all of them. depends-on becomes
+// Requires= and After=, and ready conditions hold a service in "activating"
+// until the command is ready, so its dependents only start once it is. A
+// command that a dependent waits on to exit, because of its condition or
+// because it has no ready condition, becomes a oneshot service, which systemd
+// considers started once it exits. Dependencies with the completed condition
+// use Wants= instead of Requires=, so a failure does not stop the dependent.
+// A command's hooks become ExecStartPre= and ExecStopPost=, but the config's
+// hooks are lost as targets cannot run commands. Limits become the matching
+// resource control settings, e.g. MemoryMax=. Unnamed commands are named by
+// their position, and characters that unit names do not allow become "-".
+func ToSystemd(config OneTerminalConfig) ([]SystemdUnit, []string, error) {
+	config, err := nameUnnamed(config)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var warnings []string
+	if len(config.HookConfigs()) > 0 {
+		warnings = append(warnings, "the config's before and after hooks are not exported, systemd targets cannot run commands")
+	}
+
+	unitName := func(commandName string) string {
+		return unitNamePattern.ReplaceAllString(
+			fmt.Sprintf("oneterminal-%s-%s", config.Name, commandName), "-") + ".service"
+	}
+	target := unitNamePattern.ReplaceAllString("oneterminal-"+config.Name, "-") + ".target"
+
+	commands := make(map[string]Command, len(config.Commands))
+	for _, cmd := range config.Commands {
+		commands[cmd.Name] = cmd
+	}
+	// awaitedExit are the commands that dependents wait on to exit
+	awaitedExit := make(map[string]bool)
+	for _, cmd := range config.Commands {
+		for _, dep := range cmd.DependsOn {
+			switch dep.Condition {
+			case "completed", "completed-successfully":
+				awaitedExit[dep.Name] = true
+			case "", "ready":
+				if !hasReadyCondition(commands[dep.Name]) {
+					awaitedExit[dep.Name] = true
+				}
+			}
+		}
+	}
+	for _, cmd := range config.Commands {
+		for _, dep := range cmd.DependsOn {
+			if dep.Condition == "started" && (awaitedExit[dep.Name] || hasReadyCondition(commands[dep.Name])) {
+				warnings = append(warnings, fmt.Sprintf(
+					"%q will wait for %q to be ready, systemd cannot order units after a service has only started",
+					cmd.Name, dep.Name))
+			}
+		}
+	}
+
+	var units []SystemdUnit
+	var services []string
+	serviceCommands := make(map[string]string, len(config.Commands))
+	for _, cmd := range config.Commands {
+		if other, ok := serviceCommands[unitName(cmd.Name)]; ok {
+			return nil, nil, fmt.Errorf("commands %q and %q are both named %q in systemd", other, cmd.Name, unitName(cmd.Name))
+		}
+		serviceCommands[unitName(cmd.Name)] = cmd.Name
+
+		var buf bytes.Buffer
+		fmt.Fprintf(&buf, "[Unit]\n")
+		fmt.Fprintf(&buf, "Description=%s\n", systemdEscape(fmt.Sprintf("oneterminal %s: %s", config.Name, cmd.Name)))
+		fmt.Fprintf(&buf, "PartOf=%s\n", target)
+		for _, dep := range cmd.DependsOn {
+			if dep.Condition == "completed" {
+				fmt.Fprintf(&buf, "Wants=%s\n", unitName(dep.Name))
+			} else {
+				fmt.Fprintf(&buf, "Requires=%s\n", unitName(dep.Name))
+			}
+			fmt.Fprintf(&buf, "After=%s\n", unitName(dep.Name))
+		}
+
+		fmt.Fprintf(&buf, "\n[Service]\n")
+		oneshot := awaitedExit[cmd.Name]
+		if oneshot && hasReadyCondition(cmd) {
+			warnings = append(warnings, fmt.Sprintf(
+				"%q is waited on until it exits, so its ready condition is not exported", cmd.Name))
+		}
+		if oneshot {
+			fmt.Fprintf(&buf, "Type=oneshot\n")
+			fmt.Fprintf(&buf, "RemainAfterExit=yes\n")
+		} else {
+			fmt.Fprintf(&buf, "Type=exec\n")
+		}
+		if cmd.CmdDir != "" {
+			fmt.Fprintf(&buf, "WorkingDirectory=%s\n", systemdDir(cmd.CmdDir))
+		}
+		for _, k := range sortedKeys(cmd.Environment) {
+			v := cmd.Environment[k]
+			if strings.Contains(v, "$") {
+				warnings = append(warnings, fmt.Sprintf(
+					"environment variable %s of %q references other variables, which systemd does not expand", k, cmd.Name))
+			}
+			fmt.Fprintf(&buf, "Environment=%s\n", systemdEscape(strconv.Quote(k+"="+v)))
+		}
+		if cmd.Limits != nil {
+			settings, err := systemdLimits(*cmd.Limits)
+			if err != nil {
+				return nil, nil, fmt.Errorf("command %q: invalid limits: %w", cmd.Name, err)
+			}
+			for _, setting := range settings {
+				fmt.Fprintf(&buf, "%s\n", setting)
+			}
+		}
+		if cmd.Watch != nil {
+			warnings = append(warnings, fmt.Sprintf("%q will not be restarted when its files change", cmd.Name))
+		}
+		for _, hook := range cmd.Before {
+			fmt.Fprintf(&buf, "ExecStartPre=%s\n", systemdExec(shellArgv(config, cmd, hook), true))
+		}
+		fmt.Fprintf(&buf, "ExecStart=%s\n", systemdExec(commandArgv(config, cmd), len(cmd.Argv) == 0))
+		for _, hook := range cmd.After {
+			fmt.Fprintf(&buf, "ExecStopPost=%s\n", systemdExec(shellArgv(config, cmd, hook), true))
+		}
+
+		// ExecStartPost= runs while the service is still starting, so waiting
+		// in it delays units that are ordered after this one
+		var readyCheck string
+		switch {
+		case oneshot:
+		case cmd.ReadyCheck != "":
+			readyCheck = fmt.Sprintf("until %s >/dev/null 2>&1; do sleep 1; done",
+				strings.Join(shellQuoteAll(shellArgv(config, cmd, cmd.ReadyCheck)), " "))
+		case cmd.ReadyRegexp != "":
+			readyCheck = fmt.Sprintf("journalctl --user --unit %s --follow --lines all --output cat | grep -q -E %s",
+				unitName(cmd.Name), shellQuote(cmd.ReadyRegexp))
+		}
+		if readyCheck != "" {
+			fmt.Fprintf(&buf, "ExecStartPost=/bin/sh -c %s\n", systemdQuote(readyCheck))
+			fmt.Fprintf(&buf, "TimeoutStartSec=infinity\n")
+		}
+		if cmd.Silence {
+			fmt.Fprintf(&buf, "StandardOutput=null\n")
+			fmt.Fprintf(&buf, "StandardError=null\n")
+			if cmd.ReadyRegexp != "" {
+				warnings = append(warnings, fmt.Sprintf(
+					"%q is silenced, so its ready-regexp will never match its journal", cmd.Name))
+			}
+		}
+
+		units = append(units, SystemdUnit{Name: unitName(cmd.Name), Contents: buf.Bytes()})
+		services = append(services, unitName(cmd.Name))
+	}
+
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, "[Unit]\n")
+	description := config.Short
+	if description == "" {
+		description = "oneterminal " + config.Name
+	}
+	fmt.Fprintf(&buf, "Description=%s\n", systemdEscape(description))
+	for _, service := range services {
+		fmt.Fprintf(&buf, "Wants=%s\n", service)
+	}
+	fmt.Fprintf(&buf, "\n[Install]\n")
+	fmt.Fprintf(&buf, "WantedBy=default.target\n")
+	units = append(units, SystemdUnit{Name: target, Contents: buf.Bytes()})
+
+	return units, warnings, nil
+}
+
+// hasReadyCondition returns true if cmd can become ready before it exits
+func hasReadyCondition(cmd Command) bool {
+	return cmd.ReadyRegexp != "" || cmd.ReadyCheck != ""
+}
+
+// scriptHeader defines the helpers used by the scripts of ToShellScript
+const scriptHeader = `set -o pipefail
+# run every command in its own process group, so it can be stopped along with
+# any processes it starts
+set -m
+
+logdir=$(mktemp -d)
+pids=()
+statuses=()
+
+stop() {
+	for pgid in $(jobs -p); do
+		kill -- -"$pgid" 2>/dev/null
+	done
+	wait 2>/dev/null
+	after
+	rm -rf "$logdir"
+}
+trap stop EXIT
+trap 'exit 130' INT TERM
+
+# output <name> <log file> [silent] copies stdin to the log file and prints it
+# prefixed with the command's name
+output() {
+	while IFS= read -r line || [ -n "$line" ]; do
+		printf '%s\n' "$line" >> "$2"
+		if [ -z "${3:-}" ]; then
+			printf '%s | %s\n' "$1" "$line"
+		fi
+	done
+}
+
+# running <index> <name> fails the script if a command exited before it was ready
+running() {
+	if ! kill -0 "${pids[$1]}" 2>/dev/null; then
+		echo "$2 exited before it was ready" >&2
+		exit 1
+	fi
+}
+`
+
+// ToShellScript converts a rendered config into a standalone bash script.
+// Commands start in dependency order, and each command waits for the commands
+// it depends on to reach their conditions, the same way oneterminal runs them.
+// The script stops every command when any of them fails or when it is
+// interrupted, unless a dependent only waits for the failed command to exit.
+// Before hooks run before the commands they belong to, all after hooks run
+// once the script has stopped every command. Limits are applied with nice and
+// ulimit, except for memory and cpus, which need a cgroup. Unnamed commands are
+// named by their position.
+func ToShellScript(config OneTerminalConfig) ([]byte, error) {
+	config, err := nameUnnamed(config)
+	if err != nil {
+		return nil, err
+	}
+	waves, err := config.DependencyWaves()
+	if err != nil {
+		return nil, err
+	}
+	index := make(map[string]int, len(config.Commands))
+	commands := make(map[string]Command, len(config.Commands))
+	for _, wave := range waves {
+		for _, cmd := range wave {
+			index[cmd.Name] = len(index)
+			commands[cmd.Name] = cmd
+		}
+	}
+
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, "#!/usr/bin/env bash\n")
+	fmt.Fprintf(&buf, "# %s, generated by \"oneterminal export %s --format sh\"\n", config.Name, config.Name)
+	if config.Short != "" {
+		fmt.Fprintf(&buf, "# %s\n", config.Short)
+	}
+	fmt.Fprintf(&buf, "\n%s", scriptHeader)
+
+	// hooks of the config and included configs run in their config file's
+	// directory and shell, the included configs' first and after hooks last
+	hookConfigs := config.HookConfigs()
+	hookDir := func(hooks OneTerminalConfig) Command {
+		var dir Command
+		if hooks.Path != "" {
+			dir.CmdDir = filepath.Dir(hooks.Path)
+		}
+		return dir
+	}
+	hookName := func(hooks OneTerminalConfig, kind string) string {
+		if hooks.Path != config.Path {
+			return hooks.Name + " " + kind
+		}
+		return kind
+	}
+	fmt.Fprintf(&buf, "\n# after runs the after hooks once every command has stopped\n")
+	fmt.Fprintf(&buf, "after() {\n")
+	fmt.Fprintf(&buf, "\t# keep a second ctrl+c from interrupting the cleanup\n")
+	fmt.Fprintf(&buf, "\ttrap '' INT\n")
+	for _, wave := range waves {
+		for _, cmd := range wave {
+			for _, hook := range cmd.After {
+				fmt.Fprintf(&buf, "\t%s\n", hookLine(config, cmd, cmd.Name+" after", hook))
+			}
+		}
+	}
+	for i := len(hookConfigs) - 1; i >= 0; i-- {
+		hooks := hookConfigs[i]
+		for _, hook := range hooks.After {
+			fmt.Fprintf(&buf, "\t%s\n", hookLine(hooks, hookDir(hooks), hookName(hooks, "after"), hook))
+		}
+	}
+	fmt.Fprintf(&buf, "}\n")
+	var wroteBefore bool
+	for _, hooks := range hookConfigs {
+		for _, hook := range hooks.Before {
+			if !wroteBefore {
+				fmt.Fprintf(&buf, "\n# before hooks\n")
+				wroteBefore = true
+			}
+			fmt.Fprintf(&buf, "%s || exit\n", hookLine(hooks, hookDir(hooks), hookName(hooks, "before"), hook))
+		}
+	}
+
+	w := dependencyWaits{config: config, index: index, commands: commands,
+		polled: make(map[string]bool), reaped: make(map[string]bool)}
+	for _, wave := range waves {
+		for _, cmd := range wave {
+			for _, dep := range cmd.DependsOn {
+				w.write(&buf, dep)
+			}
+
+			silent := ""
+			if cmd.Silence {
+				silent = " silent"
+			}
+			line, err := commandInvocation(config, cmd)
+			if err != nil {
+				return nil, err
+			}
+			fmt.Fprintf(&buf, "\n# %s\n", cmd.Name)
+			if cmd.Limits != nil && (cmd.Limits.Memory != "" || cmd.Limits.CPUs > 0) {
+				fmt.Fprintf(&buf, "# the memory and cpus limits are not applied, they need a cgroup\n")
+			}
+			for _, hook := range cmd.Before {
+				fmt.Fprintf(&buf, "%s || exit\n", hookLine(config, cmd, cmd.Name+" before", hook))
+			}
+			fmt.Fprintf(&buf, "%s 2>&1 | output %s \"$logdir/%d.log\"%s &\n",
+				line, shellQuote(cmd.Name), index[cmd.Name], silent)
+			fmt.Fprintf(&buf, "pids[%d]=$!\n", index[cmd.Name])
+		}
+	}
+
+	fmt.Fprintf(&buf, "\n# stop everything as soon as a command fails\n")
+	fmt.Fprintf(&buf, "for ((i = 0; i < %d; i++)); do\n", len(index)-len(w.reaped))
+	fmt.Fprintf(&buf, "\twait -n || exit\n")
+	fmt.Fprintf(&buf, "done\n")
+
+	return buf.Bytes(), nil
+}
+
+// hookLine returns a script line that runs a hook like cmd and prints its
+// output prefixed with name
+func hookLine(config OneTerminalConfig, cmd Command, name, hook string) string {
+	return fmt.Sprintf("%s 2>&1 | output %s /dev/null", invocation(cmd, shellArgv(config, cmd, hook)), shellQuote(name))
+}
+
+// dependencyWaits writes the script lines that wait for dependencies, each
+// command is waited on at most once per kind of wait
+type dependencyWaits struct {
+	config   OneTerminalConfig
+	index    map[string]int
+	commands map[string]Command
+	polled   map[string]bool // commands whose ready condition was waited on
+	reaped   map[string]bool // commands whose exit was waited on
+}
+
+// write writes the script lines that wait for dep's condition. Started
+// commands need no waiting, as commands start in dependency order
+func (w *dependencyWaits) write(buf *bytes.Buffer, dep Dependency) {
+	cmd, i := w.commands[dep.Name], w.index[dep.Name]
+	switch dep.Condition {
+	case "started":
+	case "completed":
+		w.reap(buf, cmd, i)
+	case "", "ready":
+		if !hasReadyCondition(cmd) {
+			w.reap(buf, cmd, i)
+			fmt.Fprintf(buf, "(( statuses[%d] == 0 )) || exit \"${statuses[%d]}\"\n", i, i)
+			return
+		}
+		if w.polled[dep.Name] || w.reaped[dep.Name] {
+			return
+		}
+		w.polled[dep.Name] = true
+		fmt.Fprintf(buf, "\n# wait for %s to be ready\n", cmd.Name)
+		if cmd.ReadyCheck != "" {
+			fmt.Fprintf(buf, "until %s >/dev/null 2>&1; do\n", invocation(cmd, shellArgv(w.config, cmd, cmd.ReadyCheck)))
+		} else {
+			fmt.Fprintf(buf, "until grep -q -E -- %s \"$logdir/%d.log\" 2>/dev/null; do\n", shellQuote(cmd.ReadyRegexp), i)
+		}
+		fmt.Fprintf(buf, "\trunning %d %s\n", i, shellQuote(cmd.Name))
+		fmt.Fprintf(buf, "\tsleep 1\n")
+		fmt.Fprintf(buf, "done\n")
+	case "completed-successfully":
+		w.reap(buf, cmd, i)
+		fmt.Fprintf(buf, "(( statuses[%d] == 0 )) || exit \"${statuses[%d]}\"\n", i, i)
+	}
+}
+
+// reap writes the script lines that wait for cmd to exit and record its status
+func (w *dependencyWaits) reap(buf *bytes.Buffer, cmd Command, i int) {
+	if w.reaped[cmd.Name] {
+		return
+	}
+	w.reaped[cmd.Name] = true
+	fmt.Fprintf(buf, "\n# wait for %s to exit\n", cmd.Name)
+	fmt.Fprintf(buf, "wait \"${pids[%d]}\"\n", i)
+	fmt.Fprintf(buf, "statuses[%d]=$?\n", i)
+}
+
+// commandArgv returns the program and arguments that run cmd, either its argv
+// or its command in its shell
+func commandArgv(config OneTerminalConfig, cmd Command) []string {
+	if len(cmd.Argv) > 0 {
+		return cmd.Argv
+	}
+	return shellArgv(config, cmd, cmd.Command)
+}
+
+// shellArgv returns the program and arguments that run script in cmd's shell
+func shellArgv(config OneTerminalConfig, cmd Command, script string) []string {
+	shell, args := config.CommandShell(cmd)
+	return append(append([]string{shell}, args...), script)
+}
+
+// commandInvocation returns a POSIX shell command that runs cmd itself, with
+// the limits that a shell can apply
+func commandInvocation(config OneTerminalConfig, cmd Command) (string, error) {
+	argv := commandArgv(config, cmd)
+	if cmd.Limits == nil {
+		return invocation(cmd, argv), nil
+	}
+	limits, err := cmd.Limits.CmdsyncLimits()
+	if err != nil {
+		return "", fmt.Errorf("command %q: invalid limits: %w", cmd.Name, err)
+	}
+
+	var ulimits []string
+	if limits.NoFile > 0 {
+		ulimits = append(ulimits, fmt.Sprintf("-n %d", limits.NoFile))
+	}
+	if limits.AddressSpace > 0 {
+		// in KiB, rounded up
+		ulimits = append(ulimits, fmt.Sprintf("-v %d", (limits.AddressSpace+1023)/1024))
+	}
+	if limits.CPUTime > 0 {
+		ulimits = append(ulimits, fmt.Sprintf("-t %d", cpuSeconds(limits.CPUTime)))
+	}
+	if limits.Nice != 0 {
+		argv = append([]string{"nice", "-n", strconv.Itoa(limits.Nice)}, argv...)
+	}
+	return invocation(cmd, argv, ulimits...), nil
+}
+
+// systemdLimits returns the [Service] settings that apply limits
+func systemdLimits(l Limits) ([]string, error) {
+	limits, err := l.CmdsyncLimits()
+	if err != nil {
+		return nil, err
+	}
+	var settings []string
+	if limits.Nice != 0 {
+		settings = append(settings, fmt.Sprintf("Nice=%d", limits.Nice))
+	}
+	if limits.NoFile > 0 {
+		settings = append(settings, fmt.Sprintf("LimitNOFILE=%d", limits.NoFile))
+	}
+	if limits.AddressSpace > 0 {
+		settings = append(settings, fmt.Sprintf("LimitAS=%d", limits.AddressSpace))
+	}
+	if limits.CPUTime > 0 {
+		settings = append(settings, fmt.Sprintf("LimitCPU=%d", cpuSeconds(limits.CPUTime)))
+	}
+	if limits.Memory > 0 {
+		settings = append(settings, fmt.Sprintf("MemoryMax=%d", limits.Memory))
+	}
+	if limits.CPUs > 0 {
+		settings = append(settings, fmt.Sprintf("CPUQuota=%d%%", int(math.Round(limits.CPUs*100))))
+	}
+	return settings, nil
+}
+
+// cpuSeconds rounds a CPU time limit up to whole seconds, like cmdsync does
+func cpuSeconds(d time.Duration) int64 {
+	return int64(math.Ceil(d.Seconds()))
+}
+
+// invocation returns a POSIX shell command that runs argv in cmd's directory
+// and environment, after setting any ulimits, e.g. "-n 1024". It runs in a
+// subshell, so the directory change does not leak into the calling shell
+func invocation(cmd Command, argv []string, ulimits ...string) string {
+	var parts []string
+	if cmd.CmdDir != "" {
+		parts = append(parts, fmt.Sprintf("cd %s &&", shellDir(cmd.CmdDir)))
+	}
+	for _, ulimit := range ulimits {
+		parts = append(parts, fmt.Sprintf("ulimit %s &&", ulimit))
+	}
+	for _, k := range sortedKeys(cmd.Environment) {
+		parts = append(parts, fmt.Sprintf("%s=%s", k, envQuote(cmd.Environment[k])))
+	}
+	parts = append(parts, "exec")
+	parts = append(parts, shellQuoteAll(argv)...)
+	return "(" + strings.Join(parts, " ") + ")"
+}
+
+// shellQuoteAll quotes every argument for a POSIX shell
+func shellQuoteAll(args []string) []string {
+	quoted := make([]string, len(args))
+	for i, arg := range args {
+		quoted[i] = shellQuote(arg)
+	}
+	return quoted
+}
+
+// systemdSafePattern matches arguments that need no quoting in Exec*= settings
+var systemdSafePattern = regexp.MustCompile(`^[A-Za-z0-9_@+=:,./-]+$`)
+
+// systemdExec formats a program and its arguments for Exec*= settings. Only
+// arguments that need it are quoted, except for a shell script as the last
+// argument, which always is
+func systemdExec(argv []string, script bool) string {
+	parts := []string{"/usr/bin/env"}
+	for i, arg := range argv {
+		if (script && i == len(argv)-1) || !systemdSafePattern.MatchString(arg) {
+			arg = systemdQuote(arg)
+		}
+		parts = append(parts, arg)
+	}
+	return strings.Join(parts, " ")
+}
+
+// shellDir quotes a directory for a POSIX shell, expanding "~" and environment
+// variables the same way cmdsync.ExpandDir does
+func shellDir(dir string) string {
+	if strings.HasPrefix(dir, "~") {
+		dir = "$HOME" + dir[1:]
+	}
+	return envQuote(dir)
+}
+
+// envQuote double quotes a string for a POSIX shell, leaving environment
+// variables to be expanded like oneterminal expands environment values
+func envQuote(s string) string {
+	if shellSafePattern.MatchString(s) {
+		return s
+	}
+	replacer := strings.NewReplacer(`\`, `\\`, `"`, `\"`, "`", "\\`")
+	return `"` + replacer.Replace(s) + `"`
+}
+
+// systemdDir converts a directory for WorkingDirectory=, which does not expand
+// environment variables. A leading "~" or $HOME becomes the %h specifier, other
+// environment variables are expanded now like cmdsync.ExpandDir would.
+func systemdDir(dir string) string {
+	for _, home := range []string{"~", "$HOME", "${HOME}"} {
+		if dir == home || strings.HasPrefix(dir, home+"/") {
+			return "%h" + systemdEscape(os.ExpandEnv(dir[len(home):]))
+		}
+	}
+	return systemdEscape(os.ExpandEnv(dir))
+}
+
+// systemdEscape escapes specifiers in a unit file value
+func systemdEscape(s string) string {
+	return strings.ReplaceAll(s, "%", "%%")
+}
+
+// systemdQuote double quotes a string as a single argument of a unit file
+// setting, escaping specifiers and variable substitutions
+func systemdQuote(s string) string {
+	replacer := strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`, "%", "%%", "$", "$$")
+	return `"` + replacer.Replace(s) + `"`
+}
+
+func sortedKeys(m map[string]string) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func quoteAll(ss []string) []string {
+	quoted := make([]string, len(ss))
+	for i, s := range ss {
+		quoted[i] = fmt.Sprintf("%q", s)
+	}
+	return quoted
+}
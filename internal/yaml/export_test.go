@@ -0,0 +1,510 @@
+package yaml
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestToProcfile(t *testing.T) {
+	config := OneTerminalConfig{
+		Name:  "app",
+		Shell: "bash",
+		Commands: []Command{
+			{Name: "web", Command: "npm start", CmdDir: "~/code/web", Environment: map[string]string{"PORT": "8080", "NAME": "it's $USER"}},
+			{Name: "worker", Command: "./worker", Silence: true, DependsOn: []Dependency{{Name: "web"}}},
+		},
+	}
+
+	got, warnings, err := ToProcfile(config)
+	if err != nil {
+		t.Fatalf("want nil error, got %s", err)
+	}
+	want := `web: (cd "$HOME/code/web" && NAME="it's $USER" PORT=8080 exec bash -c 'npm start')
+worker: (exec bash -c ./worker) >/dev/null 2>&1
+`
+	if string(got) != want {
+		t.Errorf("want Procfile\n%s\ngot\n%s", want, got)
+	}
+	if len(warnings) != 1 || !strings.Contains(warnings[0], `"worker" will not wait for "web"`) {
+		t.Errorf("want warning about worker's dependencies, got %q", warnings)
+	}
+
+	config.Commands[0].Command = "echo one\necho two"
+	_, _, err = ToProcfile(config)
+	if err == nil || !strings.Contains(err.Error(), "spans multiple lines") {
+		t.Errorf("want multiple lines error, got %v", err)
+	}
+}
+
+func TestToSystemd(t *testing.T) {
+	config := OneTerminalConfig{
+		Name: "app",
+		Commands: []Command{
+			{Name: "migrate", Command: "make migrate"},
+			{Name: "db", Command: "postgres", ReadyRegexp: "ready to accept"},
+			{Name: "api", Command: "echo \"100% $HOME\"", CmdDir: "~/api", DependsOn: []Dependency{{Name: "migrate"}, {Name: "db"}},
+				Environment: map[string]string{"KEY": "va\"lue"}},
+		},
+	}
+
+	units, _, err := ToSystemd(config)
+	if err != nil {
+		t.Fatalf("want nil error, got %s", err)
+	}
+	contents := make(map[string]string)
+	var names []string
+	for _, unit := range units {
+		contents[unit.Name] = string(unit.Contents)
+		names = append(names, unit.Name)
+	}
+	wantNames := "oneterminal-app-migrate.service,oneterminal-app-db.service,oneterminal-app-api.service,oneterminal-app.target"
+	if strings.Join(names, ",") != wantNames {
+		t.Fatalf("want units %s, got %s", wantNames, strings.Join(names, ","))
+	}
+
+	for unit, wantParts := range map[string][]string{
+		"oneterminal-app-migrate.service": {"Type=oneshot\n", "RemainAfterExit=yes\n",
+			"ExecStart=/usr/bin/env zsh -c \"make migrate\"\n"},
+		"oneterminal-app-db.service": {"Type=exec\n", "ExecStartPost=/bin/sh -c \"journalctl --user --unit oneterminal-app-db.service",
+			"grep -q -E 'ready to accept'\"\n", "TimeoutStartSec=infinity\n"},
+		"oneterminal-app-api.service": {
+			"Requires=oneterminal-app-migrate.service\nAfter=oneterminal-app-migrate.service\n",
+			"Requires=oneterminal-app-db.service\nAfter=oneterminal-app-db.service\n",
+			"WorkingDirectory=%h/api\n",
+			"Environment=\"KEY=va\\\"lue\"\n",
+			"ExecStart=/usr/bin/env zsh -c \"echo \\\"100%% $$HOME\\\"\"\n"},
+		"oneterminal-app.target": {"Wants=oneterminal-app-api.service\n", "WantedBy=default.target\n"},
+	} {
+		for _, part := range wantParts {
+			if !strings.Contains(contents[unit], part) {
+				t.Errorf("want %s to contain %q, got\n%s", unit, part, contents[unit])
+			}
+		}
+	}
+	if strings.Contains(contents["oneterminal-app-api.service"], "Type=oneshot") {
+		t.Errorf("want api to not be a oneshot service since nothing depends on it")
+	}
+}
+
+func TestToSystemd_Conditions(t *testing.T) {
+	config := OneTerminalConfig{
+		Name: "app",
+		Commands: []Command{
+			{Name: "cleanup", Command: "rm -rf tmp"},
+			{Name: "db", Command: "postgres", ReadyRegexp: "ready to accept"},
+			{Name: "seed", Command: "make seed", ReadyCheck: "test -f seeded"},
+			{Name: "api", Command: "go run .", DependsOn: []Dependency{
+				{Name: "cleanup", Condition: "completed"},
+				{Name: "db", Condition: "started"},
+				{Name: "seed", Condition: "completed-successfully"},
+			}},
+		},
+	}
+
+	units, warnings, err := ToSystemd(config)
+	if err != nil {
+		t.Fatalf("want nil error, got %s", err)
+	}
+	contents := make(map[string]string)
+	for _, unit := range units {
+		contents[unit.Name] = string(unit.Contents)
+	}
+
+	for unit, wantParts := range map[string][]string{
+		"oneterminal-app-cleanup.service": {"Type=oneshot\n"},
+		"oneterminal-app-seed.service":    {"Type=oneshot\n"},
+		"oneterminal-app-api.service": {
+			"Wants=oneterminal-app-cleanup.service\nAfter=oneterminal-app-cleanup.service\n",
+			"Requires=oneterminal-app-seed.service\nAfter=oneterminal-app-seed.service\n",
+		},
+	} {
+		for _, part := range wantParts {
+			if !strings.Contains(contents[unit], part) {
+				t.Errorf("want %s to contain %q, got\n%s", unit, part, contents[unit])
+			}
+		}
+	}
+	if strings.Contains(contents["oneterminal-app-seed.service"], "ExecStartPost") {
+		t.Errorf("want no ready check for a oneshot service, got\n%s", contents["oneterminal-app-seed.service"])
+	}
+
+	wantWarnings := []string{
+		`"api" will wait for "db" to be ready, systemd cannot order units after a service has only started`,
+		`"seed" is waited on until it exits, so its ready condition is not exported`,
+	}
+	if !reflect.DeepEqual(warnings, wantWarnings) {
+		t.Errorf("want warnings %q, got %q", wantWarnings, warnings)
+	}
+}
+
+func TestToShellScript(t *testing.T) {
+	if _, err := exec.LookPath("bash"); err != nil {
+		t.Skip("bash not installed")
+	}
+
+	dir := t.TempDir()
+	config := OneTerminalConfig{
+		Name:  "app",
+		Shell: "bash",
+		Commands: []Command{
+			{Name: "server", Command: "echo listening; sleep 1", ReadyRegexp: "listen",
+				DependsOn: []Dependency{{Name: "setup"}}},
+			{Name: "setup", Command: "touch setup-done", CmdDir: dir},
+			{Name: "client", Command: `test -f setup-done && echo "got $GREETING"`, CmdDir: dir,
+				Environment: map[string]string{"GREETING": "hello"}, DependsOn: []Dependency{{Name: "server"}}},
+			{Name: "quiet", Command: "echo shh", Silence: true},
+		},
+	}
+
+	script, err := ToShellScript(config)
+	if err != nil {
+		t.Fatalf("want nil error, got %s", err)
+	}
+	filename := filepath.Join(dir, "app.sh")
+	if err := os.WriteFile(filename, script, 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	// client exits first, then the script waits for server to finish
+	out, err := exec.Command("bash", filename).CombinedOutput()
+	if err != nil {
+		t.Fatalf("want script to succeed, got %s\n%s", err, out)
+	}
+	want := "server | listening\nclient | got hello\n"
+	if string(out) != want {
+		t.Errorf("want output\n%s\ngot\n%s", want, out)
+	}
+
+	config.Commands[2].Command = "exit 3"
+	script, err = ToShellScript(config)
+	if err != nil {
+		t.Fatalf("want nil error, got %s", err)
+	}
+	if err := os.WriteFile(filename, script, 0755); err != nil {
+		t.Fatal(err)
+	}
+	err = exec.Command("bash", filename).Run()
+	if exitErr, ok := err.(*exec.ExitError); !ok || exitErr.ExitCode() != 3 {
+		t.Errorf("want script to exit with the failed command's code 3, got %v", err)
+	}
+
+	config.Commands[1].DependsOn = []Dependency{{Name: "client"}}
+	_, err = ToShellScript(config)
+	if err == nil || !strings.Contains(err.Error(), "dependency cycle") {
+		t.Errorf("want dependency cycle error, got %v", err)
+	}
+}
+
+func TestToShellScript_Conditions(t *testing.T) {
+	if _, err := exec.LookPath("bash"); err != nil {
+		t.Skip("bash not installed")
+	}
+
+	dir := t.TempDir()
+	config := OneTerminalConfig{
+		Name:  "app",
+		Shell: "bash",
+		Commands: []Command{
+			{Name: "cleanup", Command: "exit 4"},
+			{Name: "migrate", Command: "touch migrated", CmdDir: dir},
+			{Name: "server", Command: "sleep 1; echo serving", ReadyRegexp: "serving"},
+			{Name: "api", Command: "test -f migrated && echo started", CmdDir: dir, DependsOn: []Dependency{
+				{Name: "cleanup", Condition: "completed"},
+				{Name: "migrate", Condition: "completed-successfully"},
+				{Name: "server", Condition: "started"},
+			}},
+		},
+	}
+
+	script, err := ToShellScript(config)
+	if err != nil {
+		t.Fatalf("want nil error, got %s", err)
+	}
+	filename := filepath.Join(dir, "app.sh")
+	if err := os.WriteFile(filename, script, 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	// the failed cleanup does not stop the script, and api does not wait for
+	// server to be ready
+	out, err := exec.Command("bash", filename).CombinedOutput()
+	if err != nil {
+		t.Fatalf("want script to succeed, got %s\n%s", err, out)
+	}
+	want := "api | started\nserver | serving\n"
+	if string(out) != want {
+		t.Errorf("want output\n%s\ngot\n%s", want, out)
+	}
+
+	config.Commands[1].Command = "exit 5"
+	script, err = ToShellScript(config)
+	if err != nil {
+		t.Fatalf("want nil error, got %s", err)
+	}
+	if err := os.WriteFile(filename, script, 0755); err != nil {
+		t.Fatal(err)
+	}
+	out, err = exec.Command("bash", filename).CombinedOutput()
+	if exitErr, ok := err.(*exec.ExitError); !ok || exitErr.ExitCode() != 5 {
+		t.Errorf("want script to exit with the failed migration's code 5, got %v", err)
+	}
+	if strings.Contains(string(out), "api |") {
+		t.Errorf("want api to not start after a failed migration, got\n%s", out)
+	}
+}
+
+func TestToSystemd_Hooks(t *testing.T) {
+	config := OneTerminalConfig{
+		Name:   "app",
+		Before: []string{"docker network create app"},
+		Commands: []Command{
+			{Name: "api", Command: "./api", Before: []string{"mkdir -p tmp"}, After: []string{"rm -rf tmp"}},
+		},
+	}
+
+	units, warnings, err := ToSystemd(config)
+	if err != nil {
+		t.Fatalf("want nil error, got %s", err)
+	}
+	api := string(units[0].Contents)
+	want := `ExecStartPre=/usr/bin/env zsh -c "mkdir -p tmp"
+ExecStart=/usr/bin/env zsh -c "./api"
+ExecStopPost=/usr/bin/env zsh -c "rm -rf tmp"
+`
+	if !strings.Contains(api, want) {
+		t.Errorf("want api unit to contain\n%s\ngot\n%s", want, api)
+	}
+	if len(warnings) != 1 || !strings.Contains(warnings[0], "the config's before and after hooks are not exported") {
+		t.Errorf("want warning about the config's hooks, got %q", warnings)
+	}
+}
+
+func TestToShellScript_Hooks(t *testing.T) {
+	if _, err := exec.LookPath("bash"); err != nil {
+		t.Skip("bash not installed")
+	}
+
+	dir := t.TempDir()
+	config := OneTerminalConfig{
+		Name:   "app",
+		Shell:  "bash",
+		Path:   filepath.Join(dir, "app.yml"),
+		Before: []string{"touch network"},
+		After:  []string{"rm network && echo cleaned up"},
+		Commands: []Command{
+			{Name: "api", Command: "echo running", CmdDir: dir,
+				Before: []string{"test -f network && echo network created"}, After: []string{"echo api stopped"}},
+		},
+	}
+
+	script, err := ToShellScript(config)
+	if err != nil {
+		t.Fatalf("want nil error, got %s", err)
+	}
+	filename := filepath.Join(dir, "app.sh")
+	if err := os.WriteFile(filename, script, 0755); err != nil {
+		t.Fatal(err)
+	}
+	out, err := exec.Command("bash", filename).CombinedOutput()
+	if err != nil {
+		t.Fatalf("want script to succeed, got %s\n%s", err, out)
+	}
+	want := "api before | network created\napi | running\napi after | api stopped\nafter | cleaned up\n"
+	if string(out) != want {
+		t.Errorf("want output\n%s\ngot\n%s", want, out)
+	}
+
+	// after hooks run even if a before hook fails
+	config.Before = []string{"touch network", "exit 6"}
+	script, err = ToShellScript(config)
+	if err != nil {
+		t.Fatalf("want nil error, got %s", err)
+	}
+	if err := os.WriteFile(filename, script, 0755); err != nil {
+		t.Fatal(err)
+	}
+	out, err = exec.Command("bash", filename).CombinedOutput()
+	if exitErr, ok := err.(*exec.ExitError); !ok || exitErr.ExitCode() != 6 {
+		t.Errorf("want script to exit with the failed hook's code 6, got %v", err)
+	}
+	if strings.Contains(string(out), "api |") || !strings.Contains(string(out), "after | cleaned up\n") {
+		t.Errorf("want api to not start and the after hooks to run, got\n%s", out)
+	}
+}
+
+func TestExport_Shells(t *testing.T) {
+	config := OneTerminalConfig{
+		Name:      "app",
+		Shell:     "bash",
+		ShellArgs: []string{"-eu", "-c"},
+		Commands: []Command{
+			{Name: "plain", Command: "echo hi"},
+			{Name: "nu", Command: "ls | length", Shell: "nu"},
+			{Name: "node", Argv: []string{"node", "server.js", "--name", "it's 100%"}},
+		},
+	}
+
+	procfile, _, err := ToProcfile(config)
+	if err != nil {
+		t.Fatalf("want nil error, got %s", err)
+	}
+	want := `plain: (exec bash -eu -c 'echo hi')
+nu: (exec nu -c 'ls | length')
+node: (exec node server.js --name 'it'\''s 100%')
+`
+	if string(procfile) != want {
+		t.Errorf("want Procfile\n%s\ngot\n%s", want, procfile)
+	}
+
+	units, _, err := ToSystemd(config)
+	if err != nil {
+		t.Fatalf("want nil error, got %s", err)
+	}
+	wantExecStarts := []string{
+		`ExecStart=/usr/bin/env bash -eu -c "echo hi"`,
+		`ExecStart=/usr/bin/env nu -c "ls | length"`,
+		`ExecStart=/usr/bin/env node server.js --name "it's 100%%"`,
+	}
+	for i, want := range wantExecStarts {
+		if !strings.Contains(string(units[i].Contents), want+"\n") {
+			t.Errorf("want %s to contain %q, got\n%s", units[i].Name, want, units[i].Contents)
+		}
+	}
+}
+
+func TestExport_Limits(t *testing.T) {
+	config := OneTerminalConfig{
+		Name: "app",
+		Commands: []Command{
+			{Name: "web", Command: "npm start", Limits: &Limits{Nice: 10, NoFile: 4096,
+				AddressSpace: "8G", CPUTime: "90s", Memory: "2G", CPUs: 1.5}},
+		},
+	}
+
+	units, _, err := ToSystemd(config)
+	if err != nil {
+		t.Fatalf("want nil error, got %s", err)
+	}
+	web := string(units[0].Contents)
+	want := `Nice=10
+LimitNOFILE=4096
+LimitAS=8589934592
+LimitCPU=90
+MemoryMax=2147483648
+CPUQuota=150%
+`
+	if !strings.Contains(web, want) {
+		t.Errorf("want web unit to contain\n%s\ngot\n%s", want, web)
+	}
+
+	procfile, warnings, err := ToProcfile(config)
+	if err != nil {
+		t.Fatalf("want nil error, got %s", err)
+	}
+	wantLine := "web: (ulimit -n 4096 && ulimit -v 8388608 && ulimit -t 90 && exec nice -n 10 zsh -c 'npm start')\n"
+	if string(procfile) != wantLine {
+		t.Errorf("want Procfile %q, got %q", wantLine, procfile)
+	}
+	if len(warnings) != 1 || !strings.Contains(warnings[0], `the memory and cpus limits of "web" are not applied`) {
+		t.Errorf("want warning about the memory and cpus limits, got %q", warnings)
+	}
+
+	config.Commands[0].Limits.Memory = "lots"
+	if _, _, err := ToSystemd(config); err == nil || !strings.Contains(err.Error(), `command "web": invalid limits: memory`) {
+		t.Errorf("want error about the invalid memory limit, got %v", err)
+	}
+}
+
+func TestExport_UnnamedCommands(t *testing.T) {
+	config := OneTerminalConfig{
+		Name:  "app",
+		Shell: "bash",
+		Commands: []Command{
+			{Command: "make watch"},
+			{Name: "backend.api", Command: "./api"},
+			{Command: "make test"},
+		},
+	}
+
+	procfile, _, err := ToProcfile(config)
+	if err != nil {
+		t.Fatalf("want nil error exporting a Procfile, got %s", err)
+	}
+	want := `unnamed-0: (exec bash -c 'make watch')
+backend-api: (exec bash -c ./api)
+unnamed-2: (exec bash -c 'make test')
+`
+	if string(procfile) != want {
+		t.Errorf("want Procfile\n%s\ngot\n%s", want, procfile)
+	}
+
+	units, _, err := ToSystemd(config)
+	if err != nil {
+		t.Fatalf("want nil error exporting systemd units, got %s", err)
+	}
+	var names []string
+	for _, unit := range units {
+		names = append(names, unit.Name)
+	}
+	wantNames := "oneterminal-app-unnamed-0.service,oneterminal-app-backend.api.service,oneterminal-app-unnamed-2.service,oneterminal-app.target"
+	if strings.Join(names, ",") != wantNames {
+		t.Errorf("want units %s, got %s", wantNames, strings.Join(names, ","))
+	}
+
+	script, err := ToShellScript(config)
+	if err != nil {
+		t.Fatalf("want nil error exporting a script, got %s", err)
+	}
+	for _, part := range []string{"pids[0]=$!", "pids[1]=$!", "pids[2]=$!"} {
+		if !strings.Contains(string(script), part) {
+			t.Errorf("want script to contain %q, got\n%s", part, script)
+		}
+	}
+
+	for _, tt := range []struct {
+		name        string
+		commands    []Command
+		wantErrPart string
+	}{
+		{
+			name:        "unnamed command name is taken",
+			commands:    []Command{{Command: "a"}, {Name: "unnamed-0", Command: "b"}},
+			wantErrPart: `unnamed command no. 0 cannot be named "unnamed-0"`,
+		},
+		{
+			name:        "process names collide",
+			commands:    []Command{{Name: "a.b", Command: "a"}, {Name: "a-b", Command: "b"}},
+			wantErrPart: `commands "a.b" and "a-b" are both named "a-b" in Procfiles`,
+		},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			_, _, err := ToProcfile(OneTerminalConfig{Name: "app", Commands: tt.commands})
+			if err == nil || !strings.Contains(err.Error(), tt.wantErrPart) {
+				t.Errorf("want error containing %q, got %v", tt.wantErrPart, err)
+			}
+		})
+	}
+}
+
+func TestSystemdDir(t *testing.T) {
+	os.Setenv("ONETERMINAL_TEST_DIR", "/srv/app")
+	defer os.Unsetenv("ONETERMINAL_TEST_DIR")
+
+	for dir, want := range map[string]string{
+		"~":                         "%h",
+		"~/api":                     "%h/api",
+		"$HOME/api":                 "%h/api",
+		"${HOME}/api":               "%h/api",
+		"$ONETERMINAL_TEST_DIR/api": "/srv/app/api",
+		"/tmp/100%":                 "/tmp/100%%",
+		"$HOMEWORK":                 "",
+	} {
+		if got := systemdDir(dir); got != want {
+			t.Errorf("systemdDir(%q) want %q, got %q", dir, want, got)
+		}
+	}
+}
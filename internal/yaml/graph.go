@@ -0,0 +1,66 @@
+package yaml
+
+import (
+	"fmt"
+	"strings"
+)
+
+// DependencyWaves groups a config's commands by the order they can start in.
+// Commands in the first wave depend on nothing, and commands in each following
+// wave only depend on commands of earlier waves. An error is returned if a
+// command depends on a missing command or if the depends-on lists form a cycle.
+func (c OneTerminalConfig) DependencyWaves() ([][]Command, error) {
+	commandNames := make(map[string]bool, len(c.Commands))
+	for _, cmd := range c.Commands {
+		commandNames[cmd.Name] = true
+	}
+	for _, cmd := range c.Commands {
+		for _, dep := range cmd.DependencyNames() {
+			if dep == cmd.Name {
+				return nil, fmt.Errorf("%q depends on itself", cmd.Name)
+			}
+			if !commandNames[dep] {
+				return nil, fmt.Errorf("%q depends-on %q, but %q does not exist", cmd.Name, dep, dep)
+			}
+		}
+	}
+
+	var waves [][]Command
+	started := make(map[string]bool, len(c.Commands))
+	remaining := c.Commands
+	for len(remaining) > 0 {
+		var wave, waiting []Command
+		for _, cmd := range remaining {
+			canStart := true
+			for _, dep := range cmd.DependencyNames() {
+				if !started[dep] {
+					canStart = false
+					break
+				}
+			}
+			if canStart {
+				wave = append(wave, cmd)
+			} else {
+				waiting = append(waiting, cmd)
+			}
+		}
+
+		if len(wave) == 0 {
+			var names []string
+			for _, cmd := range waiting {
+				names = append(names, fmt.Sprintf("%q", cmd.Name))
+			}
+			return nil, fmt.Errorf("dependency cycle between %s", strings.Join(names, ", "))
+		}
+		// mark started after the whole wave is found, so that commands do not
+		// share a wave with their dependencies
+		for _, cmd := range wave {
+			started[cmd.Name] = true
+		}
+
+		waves = append(waves, wave)
+		remaining = waiting
+	}
+
+	return waves, nil
+}
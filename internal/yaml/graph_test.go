@@ -0,0 +1,87 @@
+package yaml
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestOneTerminalConfig_DependencyWaves(t *testing.T) {
+	tests := []struct {
+		name        string
+		commands    []Command
+		wantWaves   string
+		wantErrPart string
+	}{
+		{
+			name: "independent commands share a wave",
+			commands: []Command{
+				{Name: "a"},
+				{Name: "b"},
+				{Name: ""},
+			},
+			wantWaves: "a,b,",
+		},
+		{
+			name: "dependencies start in earlier waves",
+			commands: []Command{
+				{Name: "ui", DependsOn: []Dependency{{Name: "api"}}},
+				{Name: "api", DependsOn: []Dependency{{Name: "db"}, {Name: "cache"}}},
+				{Name: "db"},
+				{Name: "cache", DependsOn: []Dependency{{Name: "db"}}},
+				{Name: "docs"},
+			},
+			wantWaves: "db,docs|cache|api|ui",
+		},
+		{
+			name: "cycle",
+			commands: []Command{
+				{Name: "a", DependsOn: []Dependency{{Name: "c"}}},
+				{Name: "b", DependsOn: []Dependency{{Name: "a"}}},
+				{Name: "c", DependsOn: []Dependency{{Name: "b"}}},
+				{Name: "d"},
+			},
+			wantErrPart: `dependency cycle between "a", "b", "c"`,
+		},
+		{
+			name: "missing dependency",
+			commands: []Command{
+				{Name: "a", DependsOn: []Dependency{{Name: "potato"}}},
+			},
+			wantErrPart: `"a" depends-on "potato", but "potato" does not exist`,
+		},
+		{
+			name: "depends on itself",
+			commands: []Command{
+				{Name: "a", DependsOn: []Dependency{{Name: "a"}}},
+			},
+			wantErrPart: `"a" depends on itself`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			waves, err := OneTerminalConfig{Commands: tt.commands}.DependencyWaves()
+			if tt.wantErrPart != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErrPart) {
+					t.Errorf("want error containing %q, got %v", tt.wantErrPart, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("want nil error, got %s", err)
+			}
+
+			var got []string
+			for _, wave := range waves {
+				var names []string
+				for _, cmd := range wave {
+					names = append(names, cmd.Name)
+				}
+				got = append(got, strings.Join(names, ","))
+			}
+			if strings.Join(got, "|") != tt.wantWaves {
+				t.Errorf("want waves %q, got %q", tt.wantWaves, strings.Join(got, "|"))
+			}
+		})
+	}
+}
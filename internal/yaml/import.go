@@ -0,0 +1,357 @@
+package yaml
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Marshal encodes a value as yaml with the indentation of config files, e.g.
+// a OneTerminalConfig as a config file
+func Marshal(v interface{}) ([]byte, error) {
+	var buf bytes.Buffer
+	encoder := yaml.NewEncoder(&buf)
+	encoder.SetIndent(2)
+	if err := encoder.Encode(v); err != nil {
+		return nil, err
+	}
+	if err := encoder.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// procfileLinePattern matches a Procfile process, e.g. "web: npm start"
+var procfileLinePattern = regexp.MustCompile(`^([A-Za-z0-9_-]+):\s*(.+)$`)
+
+// FromProcfile converts the processes of a Procfile into a config. Each process
+// becomes a command that runs in dir, the Procfile's directory.
+func FromProcfile(r io.Reader, name, dir string) (OneTerminalConfig, error) {
+	config := OneTerminalConfig{
+		Name:  name,
+		Shell: "sh",
+		Short: "imported from Procfile",
+	}
+
+	scanner := bufio.NewScanner(r)
+	for lineNum := 1; scanner.Scan(); lineNum++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		match := procfileLinePattern.FindStringSubmatch(line)
+		if match == nil {
+			return config, fmt.Errorf("line %d: invalid Procfile entry %q", lineNum, line)
+		}
+		config.Commands = append(config.Commands, Command{
+			Name:    match[1],
+			Command: match[2],
+			CmdDir:  dir,
+		})
+	}
+	if err := scanner.Err(); err != nil {
+		return config, fmt.Errorf("reading Procfile: %w", err)
+	}
+	if len(config.Commands) == 0 {
+		return config, fmt.Errorf("no processes found in Procfile")
+	}
+
+	return config, nil
+}
+
+// composeFile is the subset of the docker-compose file format that is imported
+type composeFile struct {
+	Name     string                    `yaml:"name"`
+	Services map[string]composeService `yaml:"services"`
+}
+
+type composeService struct {
+	Environment composeEnvironment `yaml:"environment"`
+	EnvFile     composeEnvFiles    `yaml:"env_file"`
+	DependsOn   composeDependsOn   `yaml:"depends_on"`
+	Healthcheck *struct {
+		Test    composeHealthcheckTest `yaml:"test"`
+		Disable bool                   `yaml:"disable"`
+	} `yaml:"healthcheck"`
+}
+
+// composeEnvironment is either a map or a list of KEY=VALUE strings. Keys
+// without values are passed through from oneterminal's environment.
+type composeEnvironment map[string]string
+
+func (e *composeEnvironment) UnmarshalYAML(value *yaml.Node) error {
+	env := make(map[string]string)
+	if value.Kind == yaml.MappingNode {
+		var m map[string]*string
+		if err := value.Decode(&m); err != nil {
+			return err
+		}
+		for k, v := range m {
+			if v == nil {
+				env[k] = "$" + k
+			} else {
+				env[k] = *v
+			}
+		}
+	} else {
+		var list []string
+		if err := value.Decode(&list); err != nil {
+			return err
+		}
+		for _, kv := range list {
+			if i := strings.Index(kv, "="); i >= 0 {
+				env[kv[:i]] = kv[i+1:]
+			} else {
+				env[kv] = "$" + kv
+			}
+		}
+	}
+	*e = env
+	return nil
+}
+
+// composeEnvFiles is a path, a list of paths or a list of {path, required}
+type composeEnvFiles []string
+
+func (f *composeEnvFiles) UnmarshalYAML(value *yaml.Node) error {
+	if value.Kind == yaml.ScalarNode {
+		*f = []string{value.Value}
+		return nil
+	}
+	for _, item := range value.Content {
+		if item.Kind == yaml.ScalarNode {
+			*f = append(*f, item.Value)
+			continue
+		}
+		var entry struct {
+			Path string `yaml:"path"`
+		}
+		if err := item.Decode(&entry); err != nil {
+			return err
+		}
+		*f = append(*f, entry.Path)
+	}
+	return nil
+}
+
+// composeDependsOn is a list of service names or a map of service names to
+// their conditions. Services in a list only need to have started, like
+// service_started.
+type composeDependsOn []Dependency
+
+// composeConditions are the oneterminal conditions of depends_on conditions
+var composeConditions = map[string]string{
+	"service_started":                "started",
+	"service_healthy":                "ready",
+	"service_completed_successfully": "completed-successfully",
+}
+
+func (d *composeDependsOn) UnmarshalYAML(value *yaml.Node) error {
+	if value.Kind == yaml.MappingNode {
+		var m map[string]struct {
+			Condition string `yaml:"condition"`
+		}
+		if err := value.Decode(&m); err != nil {
+			return err
+		}
+		for name, dep := range m {
+			if dep.Condition == "" {
+				dep.Condition = "service_started"
+			}
+			condition, ok := composeConditions[dep.Condition]
+			if !ok {
+				return fmt.Errorf("unknown depends_on condition %q of %q", dep.Condition, name)
+			}
+			*d = append(*d, Dependency{Name: name, Condition: condition})
+		}
+		sort.Slice(*d, func(i, j int) bool { return (*d)[i].Name < (*d)[j].Name })
+		return nil
+	}
+	var list []string
+	if err := value.Decode(&list); err != nil {
+		return err
+	}
+	for _, name := range list {
+		*d = append(*d, Dependency{Name: name, Condition: "started"})
+	}
+	return nil
+}
+
+// composeHealthcheckTest is the command of a healthcheck as a shell command.
+// It is empty if the healthcheck is disabled with NONE.
+type composeHealthcheckTest string
+
+func (t *composeHealthcheckTest) UnmarshalYAML(value *yaml.Node) error {
+	if value.Kind == yaml.ScalarNode {
+		*t = composeHealthcheckTest("sh -c " + shellQuote(value.Value))
+		return nil
+	}
+	var list []string
+	if err := value.Decode(&list); err != nil {
+		return err
+	}
+	if len(list) == 0 {
+		return nil
+	}
+	switch list[0] {
+	case "NONE":
+		*t = ""
+	case "CMD-SHELL":
+		*t = composeHealthcheckTest("sh -c " + shellQuote(strings.Join(list[1:], " ")))
+	case "CMD":
+		var quoted []string
+		for _, arg := range list[1:] {
+			quoted = append(quoted, shellQuote(arg))
+		}
+		*t = composeHealthcheckTest(strings.Join(quoted, " "))
+	default:
+		return fmt.Errorf("unknown healthcheck test %q", list[0])
+	}
+	return nil
+}
+
+// FromCompose converts the services of a docker-compose file into a config.
+// Each service becomes a command that runs it via `docker compose run`, with
+// the service's environment and env_file mapped to the command's environment,
+// which is passed through to the container. depends_on maps to depends-on,
+// keeping its conditions, and healthchecks are run in the service's container
+// as a ready-check.
+//
+// Warnings are returned for anything that cannot be mapped exactly.
+func FromCompose(filename string) (OneTerminalConfig, []string, error) {
+	var config OneTerminalConfig
+	contents, err := os.ReadFile(filename)
+	if err != nil {
+		return config, nil, err
+	}
+	var compose composeFile
+	if err := yaml.Unmarshal(contents, &compose); err != nil {
+		return config, nil, fmt.Errorf("unmarshalling compose file %s: %w", filename, err)
+	}
+	if len(compose.Services) == 0 {
+		return config, nil, fmt.Errorf("no services found in %s", filename)
+	}
+
+	dir, err := filepath.Abs(filepath.Dir(filename))
+	if err != nil {
+		return config, nil, err
+	}
+	config.Name = compose.Name
+	if config.Name == "" {
+		config.Name = filepath.Base(dir)
+	}
+	config.Shell = "sh"
+	config.Short = "imported from " + filepath.Base(filename)
+
+	var serviceNames []string
+	// awaitedHealthy are services that dependents wait on to be healthy
+	awaitedHealthy := make(map[string]bool)
+	for name, service := range compose.Services {
+		serviceNames = append(serviceNames, name)
+		for _, dep := range service.DependsOn {
+			if dep.Condition == "ready" {
+				awaitedHealthy[dep.Name] = true
+			}
+		}
+	}
+	sort.Strings(serviceNames)
+
+	var warnings []string
+	composeCmd := "docker compose -f " + shellQuote(filepath.Base(filename))
+	for _, name := range serviceNames {
+		service := compose.Services[name]
+
+		env := make(map[string]string)
+		for _, envFile := range service.EnvFile {
+			fileEnv, err := readEnvFile(resolveDir(dir, envFile))
+			if err != nil {
+				return config, nil, fmt.Errorf("service %q: %w", name, err)
+			}
+			for k, v := range fileEnv {
+				env[k] = v
+			}
+		}
+		for k, v := range service.Environment {
+			env[k] = v
+		}
+		var envKeys []string
+		for k := range env {
+			envKeys = append(envKeys, k)
+		}
+		sort.Strings(envKeys)
+
+		containerName := fmt.Sprintf("oneterminal-%s-%s", config.Name, name)
+		args := []string{composeCmd, "run", "--rm", "--no-deps", "--service-ports", "--name", shellQuote(containerName)}
+		for _, k := range envKeys {
+			args = append(args, "-e", fmt.Sprintf(`%s="$%s"`, k, k))
+		}
+		args = append(args, shellQuote(name))
+
+		cmd := Command{
+			Name:      name,
+			Command:   strings.Join(args, " "),
+			CmdDir:    dir,
+			DependsOn: []Dependency(service.DependsOn),
+		}
+		if len(env) > 0 {
+			cmd.Environment = env
+		}
+		if hc := service.Healthcheck; hc != nil && !hc.Disable && hc.Test != "" {
+			cmd.ReadyCheck = fmt.Sprintf("docker exec %s %s", shellQuote(containerName), hc.Test)
+		} else if awaitedHealthy[name] {
+			warnings = append(warnings, fmt.Sprintf(
+				"service %q has no healthcheck, so its dependents wait for it to exit. Consider adding a ready-regexp", name))
+		}
+
+		config.Commands = append(config.Commands, cmd)
+	}
+
+	return config, warnings, nil
+}
+
+// readEnvFile parses KEY=VALUE lines, skipping blank lines and comments
+func readEnvFile(filename string) (map[string]string, error) {
+	contents, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, fmt.Errorf("reading env_file: %w", err)
+	}
+
+	env := make(map[string]string)
+	for _, line := range strings.Split(string(contents), "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		line = strings.TrimPrefix(line, "export ")
+		i := strings.Index(line, "=")
+		if i < 0 {
+			env[line] = "$" + line
+			continue
+		}
+		key, value := strings.TrimSpace(line[:i]), strings.TrimSpace(line[i+1:])
+		if len(value) >= 2 && (value[0] == '"' || value[0] == '\'') && value[len(value)-1] == value[0] {
+			value = value[1 : len(value)-1]
+		}
+		env[key] = value
+	}
+	return env, nil
+}
+
+// shellSafePattern matches strings that do not need quoting in a shell
+var shellSafePattern = regexp.MustCompile(`^[A-Za-z0-9_@%+=:,./-]+$`)
+
+// shellQuote single quotes a string for a POSIX shell if needed
+func shellQuote(s string) string {
+	if shellSafePattern.MatchString(s) {
+		return s
+	}
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
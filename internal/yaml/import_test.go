@@ -0,0 +1,136 @@
+package yaml
+
+import (
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestFromProcfile(t *testing.T) {
+	procfile := "web: npm start\n\n# workers\nworker:   bundle exec sidekiq\n"
+	config, err := FromProcfile(strings.NewReader(procfile), "app", "/code/app")
+	if err != nil {
+		t.Fatalf("want nil error, got %s", err)
+	}
+
+	want := []Command{
+		{Name: "web", Command: "npm start", CmdDir: "/code/app"},
+		{Name: "worker", Command: "bundle exec sidekiq", CmdDir: "/code/app"},
+	}
+	if len(config.Commands) != len(want) {
+		t.Fatalf("want %d commands, got %d", len(want), len(config.Commands))
+	}
+	for i, cmd := range config.Commands {
+		if cmd.Name != want[i].Name || cmd.Command != want[i].Command || cmd.CmdDir != want[i].CmdDir {
+			t.Errorf("want command %+v, got %+v", want[i], cmd)
+		}
+	}
+	if err := validateConfig(config); err != nil {
+		t.Errorf("want imported config to be valid, got %s", err)
+	}
+
+	_, err = FromProcfile(strings.NewReader("not a process"), "app", "/code/app")
+	if err == nil || !strings.Contains(err.Error(), "line 1: invalid Procfile entry") {
+		t.Errorf("want invalid entry error, got %v", err)
+	}
+}
+
+func TestFromCompose(t *testing.T) {
+	dir := t.TempDir()
+	writeConfigFile(t, filepath.Join(dir, ".env"), "API_KEY=\"abc\"\n# comment\nexport REGION=us\n")
+	writeConfigFile(t, filepath.Join(dir, "docker-compose.yml"), `name: shop
+services:
+  db:
+    image: postgres
+    environment:
+      POSTGRES_PASSWORD: example
+    healthcheck:
+      test: ["CMD", "pg_isready", "-U", "postgres"]
+  cache:
+    image: redis
+    healthcheck:
+      test: ["NONE"]
+  migrate:
+    build: ./migrations
+  worker:
+    build: ./worker
+    depends_on: [migrate]
+  ui:
+    build: ./ui
+    depends_on:
+      cache:
+        condition: service_healthy
+  api:
+    build: .
+    env_file: .env
+    environment:
+      - PORT=8080
+      - REGION=eu
+      - DEBUG
+    depends_on:
+      db:
+        condition: service_healthy
+      cache:
+        condition: service_started
+      migrate:
+        condition: service_completed_successfully
+    healthcheck:
+      test: curl -f http://localhost:8080/health
+`)
+
+	config, warnings, err := FromCompose(filepath.Join(dir, "docker-compose.yml"))
+	if err != nil {
+		t.Fatalf("want nil error, got %s", err)
+	}
+	if config.Name != "shop" {
+		t.Errorf("want name %q, got %q", "shop", config.Name)
+	}
+	if len(warnings) != 1 || !strings.Contains(warnings[0], `service "cache" has no healthcheck`) {
+		t.Errorf("want warning about cache's healthcheck, got %q", warnings)
+	}
+	if err := validateConfig(config); err != nil {
+		t.Errorf("want imported config to be valid, got %s", err)
+	}
+
+	commands := make(map[string]Command)
+	for _, cmd := range config.Commands {
+		commands[cmd.Name] = cmd
+	}
+
+	api := commands["api"]
+	wantCommand := `docker compose -f docker-compose.yml run --rm --no-deps --service-ports --name oneterminal-shop-api ` +
+		`-e API_KEY="$API_KEY" -e DEBUG="$DEBUG" -e PORT="$PORT" -e REGION="$REGION" api`
+	if api.Command != wantCommand {
+		t.Errorf("want api command\n%s\ngot\n%s", wantCommand, api.Command)
+	}
+	wantEnv := map[string]string{"API_KEY": "abc", "DEBUG": "$DEBUG", "PORT": "8080", "REGION": "eu"}
+	for k, v := range wantEnv {
+		if api.Environment[k] != v {
+			t.Errorf("want api environment %s=%q, got %q", k, v, api.Environment[k])
+		}
+	}
+	wantDependsOn := []Dependency{
+		{Name: "cache", Condition: "started"},
+		{Name: "db", Condition: "ready"},
+		{Name: "migrate", Condition: "completed-successfully"},
+	}
+	if !reflect.DeepEqual(api.DependsOn, wantDependsOn) {
+		t.Errorf("want api to depend on %v, got %v", wantDependsOn, api.DependsOn)
+	}
+	if want := []Dependency{{Name: "migrate", Condition: "started"}}; !reflect.DeepEqual(commands["worker"].DependsOn, want) {
+		t.Errorf("want a depends_on list to only wait for services to start, got %v", commands["worker"].DependsOn)
+	}
+	if want := `docker exec oneterminal-shop-api sh -c 'curl -f http://localhost:8080/health'`; api.ReadyCheck != want {
+		t.Errorf("want api ready-check %q, got %q", want, api.ReadyCheck)
+	}
+	if want := "docker exec oneterminal-shop-db pg_isready -U postgres"; commands["db"].ReadyCheck != want {
+		t.Errorf("want db ready-check %q, got %q", want, commands["db"].ReadyCheck)
+	}
+	if commands["cache"].ReadyCheck != "" {
+		t.Errorf("want no ready-check for a NONE healthcheck, got %q", commands["cache"].ReadyCheck)
+	}
+	if api.CmdDir != dir {
+		t.Errorf("want commands to run in %q, got %q", dir, api.CmdDir)
+	}
+}
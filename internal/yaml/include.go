@@ -0,0 +1,186 @@
+package yaml
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+)
+
+// includeSeparator joins an included config's name to the names of its
+// commands, e.g. the "api" command of an included "backend" config is named
+// "backend.api", which is also the name to use in depends-on lists
+const includeSeparator = "."
+
+// resolveIncludes merges the commands of every included config into the
+// configs that include them. Includes are either the name of another config or
+// a path to a config file, relative to the including config's file. Configs
+// whose includes cannot be resolved are dropped and returned as errors.
+func resolveIncludes(configs []OneTerminalConfig) ([]OneTerminalConfig, []error) {
+	configsByName := make(map[string]OneTerminalConfig, len(configs))
+	for _, config := range configs {
+		if _, ok := configsByName[config.Name]; !ok {
+			configsByName[config.Name] = config
+		}
+	}
+
+	resolved := make([]OneTerminalConfig, 0, len(configs))
+	var errs []error
+	for _, config := range configs {
+		config, err := includeCommands(config, configsByName, nil, nil)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s: resolving includes of %q: %w", config.Path, config.Name, err))
+			continue
+		}
+		resolved = append(resolved, config)
+	}
+	return resolved, errs
+}
+
+// includeCommands recursively resolves the includes of a single config. The
+// stack of config paths that are being resolved is used to detect cycles, and
+// seen maps the files that were already included to the includes that led to
+// them, so a file that is included twice, e.g. by two included configs, is
+// reported instead of running its commands twice. Both are nil at the top.
+func includeCommands(config OneTerminalConfig, configsByName map[string]OneTerminalConfig, stack []string, seen map[string]string) (OneTerminalConfig, error) {
+	if len(config.Include) == 0 {
+		return config, nil
+	}
+	if seen == nil {
+		seen = make(map[string]string)
+	}
+	for _, p := range stack {
+		if p == config.Path {
+			return config, fmt.Errorf("include cycle: %s -> %s", strings.Join(stack, " -> "), config.Path)
+		}
+	}
+	stack = append(stack, config.Path)
+
+	var commands []Command
+	for _, include := range config.Include {
+		included, err := lookupInclude(include, config, configsByName)
+		if err != nil {
+			return config, err
+		}
+		chain := strings.Join(append(append([]string{}, stack...), included.Path), " -> ")
+		if previous, ok := seen[included.Path]; ok {
+			return config, fmt.Errorf("%s is included more than once, by %s and by %s", included.Path, previous, chain)
+		}
+		seen[included.Path] = chain
+		included, err = includeCommands(included, configsByName, stack, seen)
+		if err != nil {
+			return config, err
+		}
+		for _, cmd := range included.Commands {
+			// included commands keep running in their own config's shell
+			shell, args := included.CommandShell(cmd)
+			if parentShell, parentArgs := config.CommandShell(cmd); shell != parentShell || !reflect.DeepEqual(args, parentArgs) {
+				cmd.Shell, cmd.ShellArgs = shell, args
+			}
+			commands = append(commands, namespaceCommand(included.Name, cmd))
+		}
+		config.Params = mergeParams(config.Params, included.Params)
+		config.includedHooks = append(config.includedHooks, included.HookConfigs()...)
+	}
+	commands = append(commands, config.Commands...)
+
+	seenNames := make(map[string]bool, len(commands))
+	for _, cmd := range commands {
+		if cmd.Name != "" && seenNames[cmd.Name] {
+			return config, fmt.Errorf("duplicate command name %q after including configs", cmd.Name)
+		}
+		seenNames[cmd.Name] = true
+	}
+
+	config.Commands = commands
+	return config, nil
+}
+
+// HookConfigs returns the configs whose before and after hooks run when c runs,
+// the included configs in include order followed by c itself, leaving out any
+// without hooks. Each config's hooks run in its own directory and shell. Before
+// hooks run in this order, after hooks in reverse, so the hooks of c run right
+// before and after its commands.
+func (c OneTerminalConfig) HookConfigs() []OneTerminalConfig {
+	configs := append([]OneTerminalConfig{}, c.includedHooks...)
+	if len(c.Before) > 0 || len(c.After) > 0 {
+		configs = append(configs, OneTerminalConfig{
+			Name:      c.Name,
+			Shell:     c.Shell,
+			ShellArgs: c.ShellArgs,
+			Before:    c.Before,
+			After:     c.After,
+			Path:      c.Path,
+		})
+	}
+	return configs
+}
+
+// lookupInclude finds an included config by name, or parses it if the include
+// is a path to a yaml file
+func lookupInclude(include string, config OneTerminalConfig, configsByName map[string]OneTerminalConfig) (OneTerminalConfig, error) {
+	if !isIncludePath(include) {
+		included, ok := configsByName[include]
+		if !ok {
+			return OneTerminalConfig{}, fmt.Errorf("included config %q does not exist", include)
+		}
+		return included, nil
+	}
+
+	// expand '~' to $HOME for os.ExpandEnv to pickup
+	if include[0] == '~' {
+		include = "$HOME" + include[1:]
+	}
+	filename := resolveDir(filepath.Dir(config.Path), os.ExpandEnv(include))
+	return parseConfigFile(filename)
+}
+
+// isIncludePath reports if an include refers to a file rather than a name
+func isIncludePath(include string) bool {
+	return strings.ContainsRune(include, '/') ||
+		strings.ContainsRune(include, filepath.Separator) ||
+		isYamlPattern.MatchString(include)
+}
+
+// namespaceCommand prefixes a command's name and the names it depends on with
+// the name of the config it was included from
+func namespaceCommand(configName string, cmd Command) Command {
+	if cmd.Name != "" {
+		cmd.Name = configName + includeSeparator + cmd.Name
+	}
+	dependsOn := make([]Dependency, 0, len(cmd.DependsOn))
+	for _, dep := range cmd.DependsOn {
+		dep.Name = configName + includeSeparator + dep.Name
+		dependsOn = append(dependsOn, dep)
+	}
+	if len(dependsOn) > 0 {
+		cmd.DependsOn = dependsOn
+	}
+	return cmd
+}
+
+// mergeParams adds included params that are not already declared
+func mergeParams(params, includedParams []Param) []Param {
+	declared := make(map[string]bool, len(params))
+	for _, p := range params {
+		declared[p.Name] = true
+	}
+
+	merged := append([]Param{}, params...)
+	for _, p := range includedParams {
+		if !declared[p.Name] {
+			merged = append(merged, p)
+			declared[p.Name] = true
+		}
+	}
+	return merged
+}
+
+// defaultShell returns the shell that cmdsync uses when none is configured
+func defaultShell(shell string) string {
+	if shell == "" {
+		return "zsh"
+	}
+	return shell
+}
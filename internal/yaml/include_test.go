@@ -0,0 +1,203 @@
+package yaml
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// writeConfigFile is a testing utility that writes contents to a new file,
+// making any parent directories
+func writeConfigFile(t *testing.T, filename, contents string) {
+	if err := os.MkdirAll(filepath.Dir(filename), os.ModePerm); err != nil {
+		t.Fatalf("making directory for %s: %v", filename, err)
+	}
+	if err := os.WriteFile(filename, []byte(contents), os.ModePerm); err != nil {
+		t.Fatalf("writing file %s: %v", filename, err)
+	}
+}
+
+func TestParseAllConfigs_Includes(t *testing.T) {
+	root := t.TempDir()
+	configDir := filepath.Join(root, "configs")
+	SetConfigDirs(configDir)
+	t.Cleanup(func() { SetConfigDirs() })
+
+	writeConfigFile(t, filepath.Join(configDir, "backend.yml"), `name: backend
+commands:
+- name: db
+  command: echo db
+  ready-regexp: ready
+- name: api
+  command: echo api
+  depends-on: [db]
+`)
+	writeConfigFile(t, filepath.Join(root, "shared", "frontend.yml"), `name: frontend
+shell: bash
+commands:
+- name: ui
+  command: echo ui
+  directory: ./ui
+`)
+	writeConfigFile(t, filepath.Join(configDir, "fullstack.yml"), `name: fullstack
+include:
+- backend
+- ../shared/frontend.yml
+commands:
+- name: smoke-test
+  command: echo testing
+  depends-on: [backend.api, frontend.ui]
+`)
+
+	configs, warnings, err := parseAllConfigs(root)
+	if err != nil || len(warnings) > 0 {
+		t.Fatalf("want nil error and no warnings, got %v and %v", err, warnings)
+	}
+
+	var fullstack OneTerminalConfig
+	for _, config := range configs {
+		if config.Name == "fullstack" {
+			fullstack = config
+		}
+	}
+
+	var names []string
+	for _, cmd := range fullstack.Commands {
+		names = append(names, cmd.Name)
+	}
+	if want := "backend.db,backend.api,frontend.ui,smoke-test"; strings.Join(names, ",") != want {
+		t.Errorf("want commands %q, got %q", want, strings.Join(names, ","))
+	}
+	if got := strings.Join(fullstack.Commands[1].DependencyNames(), ","); got != "backend.db" {
+		t.Errorf("want included depends-on to be namespaced, got %q", got)
+	}
+	if got, want := fullstack.Commands[2].CmdDir, filepath.Join(root, "shared", "ui"); got != want {
+		t.Errorf("want included directory resolved to %q, got %q", want, got)
+	}
+	if got := fullstack.Commands[2].Shell; got != "bash" {
+		t.Errorf("want included command to keep its config's shell bash, got %q", got)
+	}
+	if got := fullstack.Commands[0].Shell; got != "" {
+		t.Errorf("want included command with the same shell to not set one, got %q", got)
+	}
+}
+
+func TestParseAllConfigs_IncludedHooks(t *testing.T) {
+	root := t.TempDir()
+	configDir := filepath.Join(root, "configs")
+	SetConfigDirs(configDir)
+	t.Cleanup(func() { SetConfigDirs() })
+
+	writeConfigFile(t, filepath.Join(configDir, "db.yml"), `name: db
+before: [docker start db]
+after: [docker stop db]
+commands:
+- name: migrate
+  command: echo migrate
+`)
+	writeConfigFile(t, filepath.Join(root, "shared", "cache.yml"), `name: cache
+shell: bash
+include: [../configs/db.yml]
+after: ["echo {{ .ConfigDir }}"]
+commands:
+- name: redis
+  command: echo redis
+`)
+	writeConfigFile(t, filepath.Join(configDir, "app.yml"), `name: app
+include: [../shared/cache.yml]
+before: [mkdir -p tmp]
+commands:
+- name: api
+  command: echo api
+`)
+
+	configs, warnings, err := parseAllConfigs(root)
+	if err != nil || len(warnings) > 0 {
+		t.Fatalf("want nil error and no warnings, got %v and %v", err, warnings)
+	}
+	var app OneTerminalConfig
+	for _, config := range configs {
+		if config.Name == "app" {
+			app = config
+		}
+	}
+	app, err = app.Render(nil)
+	if err != nil {
+		t.Fatalf("want nil error rendering, got %s", err)
+	}
+
+	type hooks struct {
+		name, path, shell string
+		before, after     string
+	}
+	var got []hooks
+	for _, c := range app.HookConfigs() {
+		got = append(got, hooks{c.Name, c.Path, c.Shell, strings.Join(c.Before, ";"), strings.Join(c.After, ";")})
+	}
+	want := []hooks{
+		{"db", filepath.Join(configDir, "db.yml"), "", "docker start db", "docker stop db"},
+		{"cache", filepath.Join(root, "shared", "cache.yml"), "bash", "", "echo " + filepath.Join(root, "shared")},
+		{"app", filepath.Join(configDir, "app.yml"), "", "mkdir -p tmp", ""},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("want hook configs\n%+v\ngot\n%+v", want, got)
+	}
+}
+
+func TestParseAllConfigs_IncludeErrors(t *testing.T) {
+	tests := []struct {
+		name        string
+		files       map[string]string
+		wantErrPart string
+	}{
+		{
+			name: "include cycle",
+			files: map[string]string{
+				"a.yml": "name: a\ninclude: [b]\ncommands:\n- command: echo a\n",
+				"b.yml": "name: b\ninclude: [a]\ncommands:\n- command: echo b\n",
+			},
+			wantErrPart: "include cycle",
+		},
+		{
+			name: "diamond include",
+			files: map[string]string{
+				"a.yml": "name: a\ninclude: [b, c]\n",
+				"b.yml": "name: b\ninclude: [d]\n",
+				"c.yml": "name: c\ninclude: [d]\n",
+				"d.yml": "name: d\ncommands:\n- name: db\n  command: echo d\n",
+			},
+			wantErrPart: "d.yml is included more than once, by ",
+		},
+		{
+			name: "missing include",
+			files: map[string]string{
+				"a.yml": "name: a\ninclude: [potato]\n",
+			},
+			wantErrPart: `included config "potato" does not exist`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			configDir := setupTempDir(t)
+			for filename, contents := range tt.files {
+				writeConfigFile(t, filepath.Join(configDir, filename), contents)
+			}
+
+			configs, warnings, err := parseAllConfigs(configDir)
+			if err != nil {
+				t.Fatalf("want nil error, got %s", err)
+			}
+			if len(warnings) == 0 || !strings.Contains(warnings[0].Error(), tt.wantErrPart) {
+				t.Errorf("want warning containing %q, got %v", tt.wantErrPart, warnings)
+			}
+			for _, config := range configs {
+				if config.Name == "a" {
+					t.Errorf("want config with a broken include to be skipped")
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,71 @@
+package yaml
+
+import (
+	"fmt"
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/alexchao26/oneterminal/cmdsync"
+)
+
+// sizePattern matches sizes like 512, 512K, 1.5G, 2GB or 2GiB
+var sizePattern = regexp.MustCompile(`(?i)^(\d+(?:\.\d+)?) ?(?:([kmgt])i?)?b?$`)
+
+// sizeUnits are the multipliers of size suffixes, which are powers of 1024
+// like in ulimit and docker
+var sizeUnits = map[string]float64{
+	"":  1,
+	"k": 1 << 10,
+	"m": 1 << 20,
+	"g": 1 << 30,
+	"t": 1 << 40,
+}
+
+// ParseSize parses a number of bytes with an optional K, M, G or T suffix,
+// e.g. 512M or 1.5G. Suffixes are powers of 1024 and can end in B or iB.
+func ParseSize(size string) (uint64, error) {
+	match := sizePattern.FindStringSubmatch(strings.TrimSpace(size))
+	if match == nil {
+		return 0, fmt.Errorf("invalid size %q, use a number of bytes with an optional K, M, G or T suffix", size)
+	}
+	n, err := strconv.ParseFloat(match[1], 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid size %q: %w", size, err)
+	}
+	return uint64(math.Ceil(n * sizeUnits[strings.ToLower(match[2])])), nil
+}
+
+// CmdsyncLimits parses l into the limits that are passed to a cmdsync.ShellCmd
+func (l Limits) CmdsyncLimits() (cmdsync.Limits, error) {
+	limits := cmdsync.Limits{Nice: l.Nice, NoFile: l.NoFile, CPUs: l.CPUs}
+	if l.Nice < -20 || l.Nice > 19 {
+		return limits, fmt.Errorf("nice %d is not between -20 and 19", l.Nice)
+	}
+	if l.CPUs < 0 {
+		return limits, fmt.Errorf("cpus %g cannot be negative", l.CPUs)
+	}
+
+	var err error
+	if l.AddressSpace != "" {
+		if limits.AddressSpace, err = ParseSize(l.AddressSpace); err != nil {
+			return limits, fmt.Errorf("address-space: %w", err)
+		}
+	}
+	if l.Memory != "" {
+		if limits.Memory, err = ParseSize(l.Memory); err != nil {
+			return limits, fmt.Errorf("memory: %w", err)
+		}
+	}
+	if l.CPUTime != "" {
+		if limits.CPUTime, err = time.ParseDuration(l.CPUTime); err != nil {
+			return limits, fmt.Errorf("cpu-time: %w", err)
+		}
+		if limits.CPUTime < 0 {
+			return limits, fmt.Errorf("cpu-time %s cannot be negative", l.CPUTime)
+		}
+	}
+	return limits, nil
+}
@@ -0,0 +1,106 @@
+package yaml
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/alexchao26/oneterminal/cmdsync"
+)
+
+func TestParseSize(t *testing.T) {
+	tests := []struct {
+		size        string
+		want        uint64
+		wantErrPart string
+	}{
+		{size: "512", want: 512},
+		{size: "512b", want: 512},
+		{size: "4K", want: 4096},
+		{size: "1.5G", want: 3 << 29},
+		{size: "2GB", want: 2 << 30},
+		{size: "2 GiB", want: 2 << 30},
+		{size: "1t", want: 1 << 40},
+		{size: "", wantErrPart: `invalid size ""`},
+		{size: "-1G", wantErrPart: `invalid size "-1G"`},
+		{size: "2P", wantErrPart: `invalid size "2P"`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.size, func(t *testing.T) {
+			got, err := ParseSize(tt.size)
+			if tt.wantErrPart != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErrPart) {
+					t.Errorf("want error containing %q, got %v", tt.wantErrPart, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("want nil error, got %s", err)
+			}
+			if got != tt.want {
+				t.Errorf("want %d, got %d", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestLimits_CmdsyncLimits(t *testing.T) {
+	tests := []struct {
+		name        string
+		limits      Limits
+		want        cmdsync.Limits
+		wantErrPart string
+	}{
+		{
+			name: "all limits",
+			limits: Limits{Nice: 10, NoFile: 1024, AddressSpace: "8G",
+				CPUTime: "30m", Memory: "512M", CPUs: 1.5},
+			want: cmdsync.Limits{Nice: 10, NoFile: 1024, AddressSpace: 8 << 30,
+				CPUTime: 30 * time.Minute, Memory: 512 << 20, CPUs: 1.5},
+		},
+		{
+			name:        "nice out of range",
+			limits:      Limits{Nice: -21},
+			wantErrPart: "nice -21 is not between -20 and 19",
+		},
+		{
+			name:        "negative cpus",
+			limits:      Limits{CPUs: -0.5},
+			wantErrPart: "cpus -0.5 cannot be negative",
+		},
+		{
+			name:        "invalid memory",
+			limits:      Limits{Memory: "half"},
+			wantErrPart: `memory: invalid size "half"`,
+		},
+		{
+			name:        "invalid cpu time",
+			limits:      Limits{CPUTime: "forever"},
+			wantErrPart: "cpu-time: ",
+		},
+		{
+			name:        "negative cpu time",
+			limits:      Limits{CPUTime: "-1s"},
+			wantErrPart: "cpu-time -1s cannot be negative",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tt.limits.CmdsyncLimits()
+			if tt.wantErrPart != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErrPart) {
+					t.Errorf("want error containing %q, got %v", tt.wantErrPart, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("want nil error, got %s", err)
+			}
+			if got != tt.want {
+				t.Errorf("want %+v, got %+v", tt.want, got)
+			}
+		})
+	}
+}
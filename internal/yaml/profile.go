@@ -0,0 +1,141 @@
+package yaml
+
+import (
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// Profile is a variant of a config that is selected via --profile, e.g. to run
+// the same commands against local, staging or mocked backends
+type Profile struct {
+	Environment map[string]string          `yaml:"environment,omitempty" desc:"environment variables set for every command, overriding their own values"`
+	Enable      []string                   `yaml:"enable,omitempty" desc:"names of disabled commands to run"`
+	Disable     []string                   `yaml:"disable,omitempty" desc:"names of commands to not run"`
+	Commands    map[string]CommandOverride `yaml:"commands,omitempty" desc:"overrides of command fields, keyed by command name"`
+}
+
+// CommandOverride replaces a command's fields when a profile is active. Empty
+// fields are not overridden, environment values are merged.
+type CommandOverride struct {
+	Command     string            `yaml:"command,omitempty" desc:"replaces the command to run"`
+	CmdDir      string            `yaml:"directory,omitempty" desc:"replaces the directory to run the command in"`
+	ReadyRegexp string            `yaml:"ready-regexp,omitempty" desc:"replaces the ready-regexp"`
+	ReadyCheck  string            `yaml:"ready-check,omitempty" desc:"replaces the ready-check"`
+	Environment map[string]string `yaml:"environment,omitempty" desc:"environment variables merged into the command's environment"`
+}
+
+// ProfileNames returns the sorted names of a config's profiles
+func (c OneTerminalConfig) ProfileNames() []string {
+	var names []string
+	for name := range c.Profiles {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// ApplyProfile returns a copy of the config with the named profile's overrides
+// applied and without any disabled commands. An empty name applies no profile,
+// so only commands with disabled set are removed.
+func (c OneTerminalConfig) ApplyProfile(name string) (OneTerminalConfig, error) {
+	var profile Profile
+	if name != "" {
+		var ok bool
+		profile, ok = c.Profiles[name]
+		if !ok {
+			return c, fmt.Errorf("profile %q does not exist, available profiles: [%s]",
+				name, strings.Join(c.ProfileNames(), ", "))
+		}
+	}
+
+	commandNames := make(map[string]bool, len(c.Commands))
+	for _, cmd := range c.Commands {
+		commandNames[cmd.Name] = true
+	}
+	var referenced []string
+	referenced = append(referenced, profile.Enable...)
+	referenced = append(referenced, profile.Disable...)
+	for cmdName := range profile.Commands {
+		referenced = append(referenced, cmdName)
+	}
+	for _, cmdName := range referenced {
+		if !commandNames[cmdName] {
+			return c, fmt.Errorf("profile %q references command %q, which does not exist", name, cmdName)
+		}
+	}
+
+	enabled := make(map[string]bool, len(profile.Enable))
+	for _, cmdName := range profile.Enable {
+		enabled[cmdName] = true
+	}
+	disabled := make(map[string]bool, len(profile.Disable))
+	for _, cmdName := range profile.Disable {
+		disabled[cmdName] = true
+	}
+
+	var commands []Command
+	for _, cmd := range c.Commands {
+		if disabled[cmd.Name] || (cmd.Disabled && !enabled[cmd.Name]) {
+			continue
+		}
+		cmd.Disabled = false
+
+		override := profile.Commands[cmd.Name]
+		if override.Command != "" {
+			cmd.Command = override.Command
+		}
+		if override.CmdDir != "" {
+			cmd.CmdDir = resolveDir(filepath.Dir(cmd.source), override.CmdDir)
+		}
+		if override.ReadyRegexp != "" {
+			cmd.ReadyRegexp = override.ReadyRegexp
+		}
+		if override.ReadyCheck != "" {
+			cmd.ReadyCheck = override.ReadyCheck
+		}
+		cmd.Environment = mergeEnvironments(cmd.Environment, profile.Environment, override.Environment)
+
+		commands = append(commands, cmd)
+	}
+
+	if err := checkMissingDependencies(commands); err != nil {
+		return c, err
+	}
+	c.Commands = commands
+
+	return c, nil
+}
+
+// mergeEnvironments returns a new map of all key-value pairs, later maps take
+// precedence. It returns nil if all maps are empty.
+func mergeEnvironments(envs ...map[string]string) map[string]string {
+	var merged map[string]string
+	for _, env := range envs {
+		for k, v := range env {
+			if merged == nil {
+				merged = make(map[string]string)
+			}
+			merged[k] = v
+		}
+	}
+	return merged
+}
+
+// checkMissingDependencies returns an error if a command depends on a command
+// that has been removed from the config
+func checkMissingDependencies(commands []Command) error {
+	commandNames := make(map[string]bool, len(commands))
+	for _, cmd := range commands {
+		commandNames[cmd.Name] = true
+	}
+	for _, cmd := range commands {
+		for _, dep := range cmd.DependencyNames() {
+			if !commandNames[dep] {
+				return fmt.Errorf("%q depends-on %q, which will not run", cmd.Name, dep)
+			}
+		}
+	}
+	return nil
+}
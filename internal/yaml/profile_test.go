@@ -0,0 +1,124 @@
+package yaml
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestOneTerminalConfig_ApplyProfile(t *testing.T) {
+	config := OneTerminalConfig{
+		Name: "stack",
+		Profiles: map[string]Profile{
+			"mocked": {
+				Environment: map[string]string{"BACKEND": "http://localhost:9999"},
+				Enable:      []string{"mock-server"},
+				Disable:     []string{"backend"},
+			},
+			"staging": {
+				Environment: map[string]string{"BACKEND": "https://staging.example.com"},
+				Disable:     []string{"backend"},
+				Commands: map[string]CommandOverride{
+					"ui": {
+						Command:     "npm run start:staging",
+						Environment: map[string]string{"DEBUG": "true"},
+					},
+				},
+			},
+			"broken": {
+				Disable: []string{"backend", "ui"},
+				Enable:  []string{"mock-server"},
+			},
+			"unknown-command": {
+				Disable: []string{"potato"},
+			},
+		},
+		Commands: []Command{
+			{Name: "backend", Command: "go run ."},
+			{Name: "mock-server", Command: "mockserver", Disabled: true},
+			{Name: "ui", Command: "npm start", Environment: map[string]string{"BACKEND": "http://localhost:8080"}},
+			{Name: "e2e", Command: "npm test", DependsOn: []Dependency{{Name: "ui"}}},
+		},
+	}
+
+	tests := []struct {
+		name        string
+		profile     string
+		wantNames   string
+		wantUICmd   string
+		wantUIEnv   map[string]string
+		wantErrPart string
+	}{
+		{
+			name:      "no profile removes disabled commands",
+			wantNames: "backend,ui,e2e",
+			wantUICmd: "npm start",
+			wantUIEnv: map[string]string{"BACKEND": "http://localhost:8080"},
+		},
+		{
+			name:      "enable and disable commands",
+			profile:   "mocked",
+			wantNames: "mock-server,ui,e2e",
+			wantUICmd: "npm start",
+			wantUIEnv: map[string]string{"BACKEND": "http://localhost:9999"},
+		},
+		{
+			name:      "override command fields",
+			profile:   "staging",
+			wantNames: "ui,e2e",
+			wantUICmd: "npm run start:staging",
+			wantUIEnv: map[string]string{"BACKEND": "https://staging.example.com", "DEBUG": "true"},
+		},
+		{
+			name:        "missing profile",
+			profile:     "potato",
+			wantErrPart: `profile "potato" does not exist, available profiles: [broken, mocked, staging, unknown-command]`,
+		},
+		{
+			name:        "disabling a dependency",
+			profile:     "broken",
+			wantErrPart: `"e2e" depends-on "ui", which will not run`,
+		},
+		{
+			name:        "unknown command",
+			profile:     "unknown-command",
+			wantErrPart: `references command "potato", which does not exist`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			profiled, err := config.ApplyProfile(tt.profile)
+			if tt.wantErrPart != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErrPart) {
+					t.Errorf("want error containing %q, got %v", tt.wantErrPart, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("want nil error, got %s", err)
+			}
+
+			var names []string
+			for _, cmd := range profiled.Commands {
+				names = append(names, cmd.Name)
+				if cmd.Name != "ui" {
+					continue
+				}
+				if cmd.Command != tt.wantUICmd {
+					t.Errorf("want ui command %q, got %q", tt.wantUICmd, cmd.Command)
+				}
+				if len(cmd.Environment) != len(tt.wantUIEnv) {
+					t.Errorf("want ui environment %v, got %v", tt.wantUIEnv, cmd.Environment)
+				}
+				for k, v := range tt.wantUIEnv {
+					if cmd.Environment[k] != v {
+						t.Errorf("want ui environment %s=%q, got %q", k, v, cmd.Environment[k])
+					}
+				}
+			}
+			if got := strings.Join(names, ","); got != tt.wantNames {
+				t.Errorf("want commands %q, got %q", tt.wantNames, got)
+			}
+		})
+	}
+}
@@ -0,0 +1,133 @@
+package yaml
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+// JSONSchema returns a JSON Schema (draft-07) of the config file format. It is
+// generated from the OneTerminalConfig type so it never drifts from the yaml
+// fields. Struct fields are described by their yaml, desc, enum and required
+// tags. It errors if a field has a type that typeSchema does not support.
+func JSONSchema() ([]byte, error) {
+	definitions := make(map[string]interface{})
+	schema := map[string]interface{}{
+		"$schema":     "http://json-schema.org/draft-07/schema#",
+		"$id":         "https://github.com/alexchao26/oneterminal/oneterminal.schema.json",
+		"title":       "oneterminal config",
+		"description": "A oneterminal config, see https://github.com/alexchao26/oneterminal",
+	}
+	config, err := typeSchema(reflect.TypeOf(OneTerminalConfig{}), definitions, true)
+	if err != nil {
+		return nil, err
+	}
+	for k, v := range config {
+		schema[k] = v
+	}
+	schema["definitions"] = definitions
+
+	return json.MarshalIndent(schema, "", "  ")
+}
+
+// scalarStructs are structs that can also be written as a string, see
+// Dependency.UnmarshalYAML
+var scalarStructs = map[reflect.Type]bool{
+	reflect.TypeOf(Dependency{}): true,
+}
+
+// typeSchema returns the schema of a type. Structs are added to definitions
+// and referenced by name, unless inline is set. It errors for types that have
+// no schema yet, e.g. interfaces.
+func typeSchema(t reflect.Type, definitions map[string]interface{}, inline bool) (map[string]interface{}, error) {
+	switch t.Kind() {
+	case reflect.String:
+		// yaml decodes any scalar into a string, e.g. `PORT: 8080`
+		return map[string]interface{}{"type": []string{"string", "number", "boolean"}}, nil
+	case reflect.Bool:
+		return map[string]interface{}{"type": "boolean"}, nil
+	case reflect.Int, reflect.Int64, reflect.Uint64:
+		return map[string]interface{}{"type": "integer"}, nil
+	case reflect.Float64:
+		return map[string]interface{}{"type": "number"}, nil
+	case reflect.Ptr:
+		return typeSchema(t.Elem(), definitions, inline)
+	case reflect.Slice:
+		items, err := typeSchema(t.Elem(), definitions, false)
+		if err != nil {
+			return nil, err
+		}
+		return map[string]interface{}{"type": "array", "items": items}, nil
+	case reflect.Map:
+		values, err := typeSchema(t.Elem(), definitions, false)
+		if err != nil {
+			return nil, err
+		}
+		return map[string]interface{}{"type": "object", "additionalProperties": values}, nil
+	case reflect.Struct:
+		if inline {
+			return structSchema(t, definitions)
+		}
+		if _, ok := definitions[t.Name()]; !ok {
+			// reserve the name first in case of recursive types
+			definitions[t.Name()] = nil
+			definition, err := structSchema(t, definitions)
+			if err != nil {
+				return nil, err
+			}
+			definitions[t.Name()] = definition
+		}
+		ref := map[string]interface{}{"$ref": "#/definitions/" + t.Name()}
+		if scalarStructs[t] {
+			return map[string]interface{}{
+				"oneOf": []interface{}{map[string]interface{}{"type": "string"}, ref},
+			}, nil
+		}
+		return ref, nil
+	}
+	return nil, fmt.Errorf("unsupported type %s", t)
+}
+
+// structSchema describes each of a struct's yaml fields
+func structSchema(t reflect.Type, definitions map[string]interface{}) (map[string]interface{}, error) {
+	properties := make(map[string]interface{})
+	var required []string
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		name := strings.Split(field.Tag.Get("yaml"), ",")[0]
+		if field.PkgPath != "" || name == "" || name == "-" {
+			continue
+		}
+
+		property, err := typeSchema(field.Type, definitions, false)
+		if err != nil {
+			return nil, fmt.Errorf("%s field %s: %w", t.Name(), name, err)
+		}
+		if _, ok := property["$ref"]; ok {
+			// draft-07 ignores keywords next to a $ref
+			property = map[string]interface{}{"allOf": []interface{}{property}}
+		}
+		if desc := field.Tag.Get("desc"); desc != "" {
+			property["description"] = desc
+		}
+		if enum := field.Tag.Get("enum"); enum != "" {
+			property["enum"] = strings.Split(enum, ",")
+		}
+		properties[name] = property
+
+		if field.Tag.Get("required") == "true" {
+			required = append(required, name)
+		}
+	}
+
+	schema := map[string]interface{}{
+		"type":                 "object",
+		"properties":           properties,
+		"additionalProperties": false,
+	}
+	if len(required) > 0 {
+		schema["required"] = required
+	}
+	return schema, nil
+}
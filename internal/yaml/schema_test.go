@@ -0,0 +1,71 @@
+package yaml
+
+import (
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestJSONSchema(t *testing.T) {
+	raw, err := JSONSchema()
+	if err != nil {
+		t.Fatalf("want nil error, got %s", err)
+	}
+
+	type objectSchema struct {
+		Properties map[string]struct {
+			Description string `json:"description"`
+		} `json:"properties"`
+		Required []string `json:"required"`
+	}
+	var schema struct {
+		objectSchema
+		Definitions map[string]objectSchema `json:"definitions"`
+	}
+	if err := json.Unmarshal(raw, &schema); err != nil {
+		t.Fatalf("want valid json, got %s", err)
+	}
+
+	for _, name := range []string{"name", "shell", "include", "params", "profiles", "commands"} {
+		if _, ok := schema.Properties[name]; !ok {
+			t.Errorf("want top level property %q", name)
+		}
+	}
+	if len(schema.Required) != 1 || schema.Required[0] != "name" {
+		t.Errorf("want only name to be required, got %v", schema.Required)
+	}
+	for _, def := range []string{"Command", "Dependency", "Param", "Profile", "CommandOverride", "Watch"} {
+		if _, ok := schema.Definitions[def]; !ok {
+			t.Errorf("want definition of %s", def)
+		}
+	}
+
+	// every field needs a desc tag for editors to show
+	objects := map[string]objectSchema{"OneTerminalConfig": schema.objectSchema}
+	for name, def := range schema.Definitions {
+		objects[name] = def
+	}
+	for objectName, object := range objects {
+		for name, property := range object.Properties {
+			if property.Description == "" {
+				t.Errorf("want description for %s.%s, add a desc tag", objectName, name)
+			}
+		}
+	}
+}
+
+func TestTypeSchema_UnsupportedType(t *testing.T) {
+	type Nested struct {
+		Values map[string]interface{} `yaml:"values"`
+	}
+	type Config struct {
+		Nested []Nested `yaml:"nested"`
+	}
+
+	_, err := typeSchema(reflect.TypeOf(Config{}), make(map[string]interface{}), true)
+	wantErrPart := "Config field nested: Nested field values: unsupported type interface {}"
+	if err == nil || !strings.Contains(err.Error(), wantErrPart) {
+		t.Errorf("want error containing %q, got %v", wantErrPart, err)
+	}
+}
@@ -0,0 +1,76 @@
+package yaml
+
+import "fmt"
+
+// Select returns a copy of the config with only a subset of its commands.
+//
+// If only is not empty, just the commands named in only and the commands they
+// transitively depend on are kept. Commands named in except are then removed,
+// which is an error if a remaining command depends on them.
+func (c OneTerminalConfig) Select(only, except []string) (OneTerminalConfig, error) {
+	commandsByName := make(map[string]Command, len(c.Commands))
+	for _, cmd := range c.Commands {
+		commandsByName[cmd.Name] = cmd
+	}
+	for _, names := range [][]string{only, except} {
+		for _, name := range names {
+			if _, ok := commandsByName[name]; !ok || name == "" {
+				return c, fmt.Errorf("command %q does not exist", name)
+			}
+		}
+	}
+
+	selected := make(map[string]bool, len(c.Commands))
+	if len(only) == 0 {
+		for _, cmd := range c.Commands {
+			selected[cmd.Name] = true
+		}
+	} else {
+		// depth first search through the depends-on lists
+		stack := append([]string{}, only...)
+		for len(stack) > 0 {
+			name := stack[len(stack)-1]
+			stack = stack[:len(stack)-1]
+			if selected[name] {
+				continue
+			}
+			selected[name] = true
+			stack = append(stack, commandsByName[name].DependencyNames()...)
+		}
+	}
+
+	excluded := make(map[string]bool, len(except))
+	for _, name := range except {
+		excluded[name] = true
+	}
+
+	var commands []Command
+	for _, cmd := range c.Commands {
+		if !selected[cmd.Name] || excluded[cmd.Name] {
+			continue
+		}
+		for _, dep := range cmd.DependencyNames() {
+			if excluded[dep] {
+				return c, fmt.Errorf("cannot exclude %q, %q depends on it", dep, cmd.Name)
+			}
+		}
+		commands = append(commands, cmd)
+	}
+	if len(commands) == 0 {
+		return c, fmt.Errorf("no commands selected")
+	}
+	c.Commands = commands
+
+	return c, nil
+}
+
+// CommandNames returns the names of a config's named commands
+func (c OneTerminalConfig) CommandNames() []string {
+	var names []string
+	for _, cmd := range c.Commands {
+		if cmd.Name != "" {
+			names = append(names, cmd.Name)
+		}
+	}
+	return names
+}
@@ -0,0 +1,88 @@
+package yaml
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestOneTerminalConfig_Select(t *testing.T) {
+	config := OneTerminalConfig{
+		Name: "stack",
+		Commands: []Command{
+			{Name: "db", Command: "postgres"},
+			{Name: "migrate", Command: "make migrate", DependsOn: []Dependency{{Name: "db"}}},
+			{Name: "api", Command: "go run .", DependsOn: []Dependency{{Name: "migrate"}}},
+			{Name: "worker", Command: "go run ./worker", DependsOn: []Dependency{{Name: "db"}}},
+			{Name: "ui", Command: "npm start", DependsOn: []Dependency{{Name: "api"}}},
+			{Name: "", Command: "vault login"},
+		},
+	}
+
+	tests := []struct {
+		name        string
+		only        []string
+		except      []string
+		wantNames   string
+		wantErrPart string
+	}{
+		{
+			name:      "no selection keeps all commands",
+			wantNames: "db,migrate,api,worker,ui,",
+		},
+		{
+			name:      "only includes transitive dependencies",
+			only:      []string{"api"},
+			wantNames: "db,migrate,api",
+		},
+		{
+			name:      "only multiple commands",
+			only:      []string{"worker", "migrate"},
+			wantNames: "db,migrate,worker",
+		},
+		{
+			name:      "except a command without dependants",
+			except:    []string{"ui"},
+			wantNames: "db,migrate,api,worker,",
+		},
+		{
+			name:      "only and except",
+			only:      []string{"api", "worker"},
+			except:    []string{"worker"},
+			wantNames: "db,migrate,api",
+		},
+		{
+			name:        "except a dependency",
+			only:        []string{"api"},
+			except:      []string{"db"},
+			wantErrPart: `cannot exclude "db", "migrate" depends on it`,
+		},
+		{
+			name:        "unknown command",
+			only:        []string{"potato"},
+			wantErrPart: `command "potato" does not exist`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			selected, err := config.Select(tt.only, tt.except)
+			if tt.wantErrPart != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErrPart) {
+					t.Errorf("want error containing %q, got %v", tt.wantErrPart, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("want nil error, got %s", err)
+			}
+
+			var names []string
+			for _, cmd := range selected.Commands {
+				names = append(names, cmd.Name)
+			}
+			if got := strings.Join(names, ","); got != tt.wantNames {
+				t.Errorf("want commands %q, got %q", tt.wantNames, got)
+			}
+		})
+	}
+}
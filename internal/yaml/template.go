@@ -0,0 +1,243 @@
+package yaml
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"text/template"
+)
+
+// templateData is available to templates in a command's fields
+type templateData struct {
+	Params    map[string]string // param values, see OneTerminalConfig.Params
+	Env       map[string]string // environment variables of oneterminal
+	ConfigDir string            // directory of the yaml file declaring the command
+	OS        string            // runtime.GOOS, e.g. linux or darwin
+	Arch      string            // runtime.GOARCH, e.g. amd64 or arm64
+}
+
+// templateFuncs are helper functions available to templates
+//     default: {{ env "PORT" | default "8080" }} falls back to a default value,
+//         use env rather than .Env for variables that may be unset, as missing
+//         .Env keys are an error
+//     required: {{ required "port is required" .Params.port }} errors if empty
+//     env: {{ env "USER" }} returns an environment variable
+//     file: {{ file "token.txt" }} returns a file's contents, trailing newlines
+//         are trimmed and relative paths are resolved against the ConfigDir
+func templateFuncs(configDir string, env map[string]string) template.FuncMap {
+	return template.FuncMap{
+		"default": func(fallback string, value interface{}) string {
+			if value == nil || fmt.Sprint(value) == "" {
+				return fallback
+			}
+			return fmt.Sprint(value)
+		},
+		"required": func(msg string, value interface{}) (string, error) {
+			if value == nil || fmt.Sprint(value) == "" {
+				return "", fmt.Errorf("%s", msg)
+			}
+			return fmt.Sprint(value), nil
+		},
+		"env": func(key string) string {
+			return env[key]
+		},
+		"file": func(filename string) (string, error) {
+			contents, err := os.ReadFile(resolveDir(configDir, filename))
+			if err != nil {
+				return "", err
+			}
+			return strings.TrimRight(string(contents), "\r\n"), nil
+		},
+	}
+}
+
+// Render returns a copy of the config with its hooks and each command's command
+// string, argv, directory, environment values, ready-regexp, ready-check and
+// hooks rendered via text/template,
+// e.g. {{ .Params.branch }}. See templateData and templateFuncs for everything
+// that is available to templates.
+//
+// params are the values passed in by the user, any params that are not passed
+// in use their default value. An error is returned if a required param is
+// missing or an undeclared param is passed in.
+func (c OneTerminalConfig) Render(params map[string]string) (OneTerminalConfig, error) {
+	return c.render(params, nil, "")
+}
+
+// RenderMasked renders the config like Render, but substitutes mask for the
+// values of the params and environment variables whose names secret reports,
+// so the rendered config can be printed without leaking them. Secrets that are
+// written into the config itself or read via the file func are not masked.
+func (c OneTerminalConfig) RenderMasked(params map[string]string, secret func(name string) bool, mask string) (OneTerminalConfig, error) {
+	return c.render(params, secret, mask)
+}
+
+func (c OneTerminalConfig) render(params map[string]string, secret func(name string) bool, mask string) (OneTerminalConfig, error) {
+	data := templateData{
+		Params: make(map[string]string, len(c.Params)),
+		Env:    make(map[string]string),
+		OS:     runtime.GOOS,
+		Arch:   runtime.GOARCH,
+	}
+	for _, kv := range os.Environ() {
+		if i := strings.Index(kv, "="); i > 0 {
+			data.Env[kv[:i]] = kv[i+1:]
+		}
+	}
+
+	declared := make(map[string]bool, len(c.Params))
+	for _, p := range c.Params {
+		declared[p.Name] = true
+		value, ok := params[p.Name]
+		if !ok {
+			value = p.Default
+		}
+		if p.Required && value == "" {
+			return c, fmt.Errorf("missing required param %q", p.Name)
+		}
+		data.Params[p.Name] = value
+	}
+	for name := range params {
+		if !declared[name] {
+			return c, fmt.Errorf("param %q is not declared", name)
+		}
+	}
+	if secret != nil {
+		for _, values := range []map[string]string{data.Params, data.Env} {
+			for name, value := range values {
+				if value != "" && secret(name) {
+					values[name] = mask
+				}
+			}
+		}
+	}
+
+	c, err := c.renderHooks(data)
+	if err != nil {
+		return c, err
+	}
+	includedHooks := make([]OneTerminalConfig, 0, len(c.includedHooks))
+	for _, hooks := range c.includedHooks {
+		rendered, err := hooks.renderHooks(data)
+		if err != nil {
+			return c, err
+		}
+		includedHooks = append(includedHooks, rendered)
+	}
+	c.includedHooks = includedHooks
+
+	commands := make([]Command, 0, len(c.Commands))
+	for _, cmd := range c.Commands {
+		rendered, err := cmd.render(data)
+		if err != nil {
+			return c, fmt.Errorf("%s: command %q: %w", cmd.source, cmd.Name, err)
+		}
+		commands = append(commands, rendered)
+	}
+	c.Commands = commands
+
+	return c, nil
+}
+
+// renderHooks renders the config's before and after hooks, relative to the
+// config's own file
+func (c OneTerminalConfig) renderHooks(data templateData) (OneTerminalConfig, error) {
+	data.ConfigDir = filepath.Dir(c.Path)
+	funcs := templateFuncs(data.ConfigDir, data.Env)
+	var err error
+	if c.Before, err = renderList("before hook", c.Before, data, funcs); err != nil {
+		return c, fmt.Errorf("%s: %w", c.Path, err)
+	}
+	if c.After, err = renderList("after hook", c.After, data, funcs); err != nil {
+		return c, fmt.Errorf("%s: %w", c.Path, err)
+	}
+	return c, nil
+}
+
+func (cmd Command) render(data templateData) (Command, error) {
+	data.ConfigDir = filepath.Dir(cmd.source)
+	funcs := templateFuncs(data.ConfigDir, data.Env)
+
+	var err error
+	cmd.Command, err = renderField("command", cmd.Command, data, funcs)
+	if err != nil {
+		return cmd, err
+	}
+
+	if cmd.Argv, err = renderList("argv", cmd.Argv, data, funcs); err != nil {
+		return cmd, err
+	}
+
+	cmd.CmdDir, err = renderField("directory", cmd.CmdDir, data, funcs)
+	if err != nil {
+		return cmd, err
+	}
+	cmd.CmdDir = resolveDir(data.ConfigDir, cmd.CmdDir)
+
+	if cmd.Environment != nil {
+		env := make(map[string]string, len(cmd.Environment))
+		for k, v := range cmd.Environment {
+			env[k], err = renderField("environment "+k, v, data, funcs)
+			if err != nil {
+				return cmd, err
+			}
+		}
+		cmd.Environment = env
+	}
+
+	cmd.ReadyRegexp, err = renderField("ready-regexp", cmd.ReadyRegexp, data, funcs)
+	if err != nil {
+		return cmd, err
+	}
+
+	cmd.ReadyCheck, err = renderField("ready-check", cmd.ReadyCheck, data, funcs)
+	if err != nil {
+		return cmd, err
+	}
+
+	if cmd.Before, err = renderList("before hook", cmd.Before, data, funcs); err != nil {
+		return cmd, err
+	}
+	if cmd.After, err = renderList("after hook", cmd.After, data, funcs); err != nil {
+		return cmd, err
+	}
+
+	return cmd, nil
+}
+
+// renderList renders each item of a list field, like argv or hooks, into a new
+// slice, so the unrendered config is not modified
+func renderList(field string, list []string, data templateData, funcs template.FuncMap) ([]string, error) {
+	if list == nil {
+		return nil, nil
+	}
+	rendered := make([]string, len(list))
+	for i, item := range list {
+		var err error
+		rendered[i], err = renderField(fmt.Sprintf("%s no. %d", field, i), item, data, funcs)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return rendered, nil
+}
+
+// renderField executes a single field's template, fields without any actions
+// are returned as is
+func renderField(field, text string, data templateData, funcs template.FuncMap) (string, error) {
+	if !strings.Contains(text, "{{") {
+		return text, nil
+	}
+
+	tmpl, err := template.New(field).Funcs(funcs).Option("missingkey=error").Parse(text)
+	if err != nil {
+		return "", fmt.Errorf("parsing %s: %w", field, err)
+	}
+	var sb strings.Builder
+	if err := tmpl.Execute(&sb, data); err != nil {
+		return "", fmt.Errorf("executing %s: %w", field, err)
+	}
+	return sb.String(), nil
+}
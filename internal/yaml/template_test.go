@@ -0,0 +1,209 @@
+package yaml
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+)
+
+func TestOneTerminalConfig_Render(t *testing.T) {
+	config := OneTerminalConfig{
+		Name: "api",
+		Params: []Param{
+			{Name: "branch", Required: true},
+			{Name: "port", Default: "8080"},
+		},
+		After: []string{"echo stopped {{ .Params.branch }}"},
+		Commands: []Command{
+			{
+				Name:        "server",
+				Command:     "git checkout {{ .Params.branch }} && ./server --port {{ .Params.port }}",
+				CmdDir:      "{{ .Params.branch }}",
+				Environment: map[string]string{"PORT": "{{ .Params.port }}", "STATIC": "static"},
+				Before:      []string{"fuser -k {{ .Params.port }}/tcp"},
+				source:      "/repos/api.yml",
+			},
+		},
+	}
+
+	tests := []struct {
+		name        string
+		params      map[string]string
+		wantCommand string
+		wantDir     string
+		wantPortEnv string
+		wantBefore  string
+		wantAfter   string
+		wantErrPart string
+	}{
+		{
+			name:        "defaults are used for missing params",
+			params:      map[string]string{"branch": "main"},
+			wantCommand: "git checkout main && ./server --port 8080",
+			wantDir:     "/repos/main",
+			wantPortEnv: "8080",
+			wantBefore:  "fuser -k 8080/tcp",
+			wantAfter:   "echo stopped main",
+		},
+		{
+			name:        "passed params override defaults",
+			params:      map[string]string{"branch": "/abs/feature-x", "port": "9000"},
+			wantCommand: "git checkout /abs/feature-x && ./server --port 9000",
+			wantDir:     "/abs/feature-x",
+			wantPortEnv: "9000",
+			wantBefore:  "fuser -k 9000/tcp",
+			wantAfter:   "echo stopped /abs/feature-x",
+		},
+		{
+			name:        "missing required param",
+			params:      map[string]string{"port": "9000"},
+			wantErrPart: `missing required param "branch"`,
+		},
+		{
+			name:        "undeclared param",
+			params:      map[string]string{"branch": "main", "potato": "yes"},
+			wantErrPart: `param "potato" is not declared`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rendered, err := config.Render(tt.params)
+			if tt.wantErrPart != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErrPart) {
+					t.Errorf("want error containing %q, got %v", tt.wantErrPart, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("want nil error, got %s", err)
+			}
+
+			cmd := rendered.Commands[0]
+			if cmd.Command != tt.wantCommand {
+				t.Errorf("want command %q, got %q", tt.wantCommand, cmd.Command)
+			}
+			if cmd.CmdDir != tt.wantDir {
+				t.Errorf("want directory %q, got %q", tt.wantDir, cmd.CmdDir)
+			}
+			if got := cmd.Environment["PORT"]; got != tt.wantPortEnv {
+				t.Errorf("want PORT environment %q, got %q", tt.wantPortEnv, got)
+			}
+			if got := cmd.Environment["STATIC"]; got != "static" {
+				t.Errorf("want STATIC environment %q, got %q", "static", got)
+			}
+			if got := cmd.Before[0]; got != tt.wantBefore {
+				t.Errorf("want before hook %q, got %q", tt.wantBefore, got)
+			}
+			if got := rendered.After[0]; got != tt.wantAfter {
+				t.Errorf("want config after hook %q, got %q", tt.wantAfter, got)
+			}
+		})
+	}
+
+	if config.Commands[0].Environment["PORT"] != "{{ .Params.port }}" || config.After[0] != "echo stopped {{ .Params.branch }}" {
+		t.Errorf("want Render to not modify the original config")
+	}
+}
+
+func TestOneTerminalConfig_Render_TemplateData(t *testing.T) {
+	configDir := t.TempDir()
+	writeConfigFile(t, filepath.Join(configDir, "token.txt"), "s3cret\n")
+	os.Setenv("ONETERMINAL_TEST_VAR", "potato")
+	defer os.Unsetenv("ONETERMINAL_TEST_VAR")
+
+	tests := []struct {
+		name        string
+		command     string
+		readyRegexp string
+		want        string
+		wantErrPart string
+	}{
+		{
+			name:    "env map and func",
+			command: "{{ .Env.ONETERMINAL_TEST_VAR }} {{ env \"ONETERMINAL_TEST_VAR\" }}",
+			want:    "potato potato",
+		},
+		{
+			name:    "default func",
+			command: "{{ .Params.empty | default \"fallback\" }}",
+			want:    "fallback",
+		},
+		{
+			name:    "default func with unset env var",
+			command: "{{ env \"ONETERMINAL_DOES_NOT_EXIST\" | default \"fallback\" }}",
+			want:    "fallback",
+		},
+		{
+			name:    "file func reads relative to config dir",
+			command: "TOKEN={{ file \"token.txt\" }}",
+			want:    "TOKEN=s3cret",
+		},
+		{
+			name:    "config dir, os and arch",
+			command: "{{ .ConfigDir }} {{ .OS }}/{{ .Arch }}",
+			want:    configDir + " " + runtime.GOOS + "/" + runtime.GOARCH,
+		},
+		{
+			name:    "escaped braces stay literal",
+			command: `docker inspect --format '{{"{{"}}.State.Status}}' {{ .Params.empty | default "db" }}`,
+			want:    "docker inspect --format '{{.State.Status}}' db",
+		},
+		{
+			name:        "unescaped braces of other tools error",
+			command:     "docker inspect --format '{{.State.Status}}' db",
+			wantErrPart: `can't evaluate field State`,
+		},
+		{
+			name:        "ready-regexp is rendered",
+			command:     "echo",
+			readyRegexp: "listening on {{ .Params.empty | default \"8080\" }}",
+			want:        "echo",
+		},
+		{
+			name:    "required func errors with file and command name",
+			command: "{{ required \"empty must be set\" .Params.empty }}",
+			wantErrPart: filepath.Join(configDir, "app.yml") + `: command "app": ` +
+				`executing command: template: command:1:3: executing "command" at <required "empty must be set" .Params.empty>: error calling required: empty must be set`,
+		},
+		{
+			name:        "missing env var errors",
+			command:     "{{ .Env.ONETERMINAL_DOES_NOT_EXIST }}",
+			wantErrPart: `map has no entry for key "ONETERMINAL_DOES_NOT_EXIST"`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := OneTerminalConfig{
+				Name:   "app",
+				Params: []Param{{Name: "empty"}},
+				Commands: []Command{{
+					Name:        "app",
+					Command:     tt.command,
+					ReadyRegexp: tt.readyRegexp,
+					source:      filepath.Join(configDir, "app.yml"),
+				}},
+			}
+
+			rendered, err := config.Render(nil)
+			if tt.wantErrPart != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErrPart) {
+					t.Errorf("want error containing %q, got %v", tt.wantErrPart, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("want nil error, got %s", err)
+			}
+			if got := rendered.Commands[0].Command; got != tt.want {
+				t.Errorf("want command %q, got %q", tt.want, got)
+			}
+			if tt.readyRegexp != "" && rendered.Commands[0].ReadyRegexp != "listening on 8080" {
+				t.Errorf("want rendered ready-regexp, got %q", rendered.Commands[0].ReadyRegexp)
+			}
+		})
+	}
+}
@@ -0,0 +1,527 @@
+package yaml
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/alexchao26/oneterminal/cmdsync"
+	"gopkg.in/yaml.v3"
+)
+
+// Problem is an issue in a config file that is found by Validate
+type Problem struct {
+	File    string
+	Line    int // zero if the line is unknown
+	Message string
+
+	// path of yaml keys and sequence indexes to the problem, used to look up
+	// its line, e.g. ["commands", 2, "ready-regexp"]
+	keys []interface{}
+}
+
+func (p Problem) String() string {
+	if p.Line == 0 {
+		return fmt.Sprintf("%s: %s", p.File, p.Message)
+	}
+	return fmt.Sprintf("%s:%d: %s", p.File, p.Line, p.Message)
+}
+
+// shellPattern matches shell names and paths, cmdsync passes arguments to the
+// shell separately, see shell-args
+var shellPattern = regexp.MustCompile(`^\S+$`)
+
+// Validate thoroughly checks every config file that ParseAllConfigs reads and
+// returns all of the problems that are found, sorted by file and line.
+//
+// Unlike ParseAllConfigs, unknown yaml keys, invalid regexps, missing
+// directories and dependency cycles are also reported.
+func Validate() ([]Problem, error) {
+	wd, err := os.Getwd()
+	if err != nil {
+		return nil, fmt.Errorf("getting working directory: %w", err)
+	}
+	return validate(wd)
+}
+
+func validate(workDir string) ([]Problem, error) {
+	sources, err := configSources(workDir)
+	if err != nil {
+		return nil, err
+	}
+
+	var problems []Problem
+	var configs []OneTerminalConfig
+	roots := make(map[string]*yaml.Node)
+	shadowed := make(map[string]bool)
+	for _, filenames := range sources {
+		var sourceConfigs []OneTerminalConfig
+		for _, filename := range filenames {
+			config, root, fileProblems := lintConfigFile(filename)
+			problems = append(problems, fileProblems...)
+			if root == nil || config.Name == "" || shadowed[config.Name] {
+				continue
+			}
+			roots[filename] = root
+			sourceConfigs = append(sourceConfigs, config)
+		}
+		for _, config := range sourceConfigs {
+			shadowed[config.Name] = true
+		}
+		configs = append(configs, sourceConfigs...)
+	}
+
+	_, collisions := nameCollisions(configs)
+	problems = append(problems, collisions...)
+
+	configsByName := make(map[string]OneTerminalConfig, len(configs))
+	for _, config := range configs {
+		if _, ok := configsByName[config.Name]; !ok {
+			configsByName[config.Name] = config
+		}
+	}
+	for _, config := range configs {
+		problems = append(problems, checkResolvedConfig(config, configsByName)...)
+	}
+
+	for i, p := range problems {
+		if root, ok := roots[p.File]; ok && p.Line == 0 {
+			problems[i].Line = lineOf(root, p.keys)
+		}
+	}
+	sort.SliceStable(problems, func(i, j int) bool {
+		if problems[i].File != problems[j].File {
+			return problems[i].File < problems[j].File
+		}
+		return problems[i].Line < problems[j].Line
+	})
+
+	return problems, nil
+}
+
+// ValidateFile checks a single config file like Validate does and returns its
+// problems. Files outside of the config directories, e.g. one that is opened by
+// its path, are checked on their own, without problems like name collisions
+// that involve other configs.
+func ValidateFile(filename string) ([]Problem, error) {
+	wd, err := os.Getwd()
+	if err != nil {
+		return nil, fmt.Errorf("getting working directory: %w", err)
+	}
+	return validateFile(wd, filename)
+}
+
+func validateFile(workDir, filename string) ([]Problem, error) {
+	sources, err := configSources(workDir)
+	if err != nil {
+		return nil, err
+	}
+	for _, filenames := range sources {
+		for _, source := range filenames {
+			if source != filename {
+				continue
+			}
+			problems, err := validate(workDir)
+			if err != nil {
+				return nil, err
+			}
+			var fileProblems []Problem
+			for _, p := range problems {
+				if p.File == filename {
+					fileProblems = append(fileProblems, p)
+				}
+			}
+			return fileProblems, nil
+		}
+	}
+
+	_, root, problems := lintConfigFile(filename)
+	for i, p := range problems {
+		if root != nil && p.Line == 0 {
+			problems[i].Line = lineOf(root, p.keys)
+		}
+	}
+	sort.SliceStable(problems, func(i, j int) bool {
+		return problems[i].Line < problems[j].Line
+	})
+	return problems, nil
+}
+
+// lintConfigFile strictly decodes a config file and checks it in isolation. A
+// nil root node is returned if the file could not be decoded at all.
+func lintConfigFile(filename string) (OneTerminalConfig, *yaml.Node, []Problem) {
+	var config OneTerminalConfig
+	contents, err := os.ReadFile(filename)
+	if err != nil {
+		return config, nil, []Problem{{File: filename, Message: err.Error()}}
+	}
+
+	var root yaml.Node
+	if err := yaml.Unmarshal(contents, &root); err != nil {
+		return config, nil, yamlErrorProblems(filename, err)
+	}
+	if len(root.Content) == 0 {
+		return config, nil, []Problem{{File: filename, Message: "empty config file"}}
+	}
+
+	var problems []Problem
+	decoder := yaml.NewDecoder(bytes.NewReader(contents))
+	decoder.KnownFields(true)
+	if err := decoder.Decode(&config); err != nil && !errors.Is(err, io.EOF) {
+		problems = append(problems, yamlErrorProblems(filename, err)...)
+		var typeErr *yaml.TypeError
+		if !errors.As(err, &typeErr) {
+			return config, nil, problems
+		}
+		// type errors are not fatal, decode leniently to check the rest
+		config = OneTerminalConfig{}
+		if err := root.Decode(&config); err != nil {
+			return config, nil, problems
+		}
+	}
+
+	config.setPath(filename)
+
+	for _, p := range checkConfig(config, true) {
+		p.File = filename
+		problems = append(problems, p)
+	}
+	return config, &root, problems
+}
+
+// yamlLinePattern matches the line numbers in errors from the yaml package
+var yamlLinePattern = regexp.MustCompile(`line (\d+): (.*)`)
+
+// yamlErrorProblems converts syntax and type errors into problems with lines
+func yamlErrorProblems(filename string, err error) []Problem {
+	messages := []string{err.Error()}
+	var typeErr *yaml.TypeError
+	if errors.As(err, &typeErr) {
+		messages = typeErr.Errors
+	}
+
+	var problems []Problem
+	for _, msg := range messages {
+		p := Problem{File: filename, Message: strings.TrimPrefix(msg, "yaml: ")}
+		if match := yamlLinePattern.FindStringSubmatch(msg); match != nil {
+			p.Line, _ = strconv.Atoi(match[1])
+			p.Message = match[2]
+		}
+		problems = append(problems, p)
+	}
+	return problems
+}
+
+// checkConfig returns the problems of a single config. Only required fields
+// are checked unless thorough is set.
+func checkConfig(config OneTerminalConfig, thorough bool) []Problem {
+	var problems []Problem
+	add := func(msg string, keys ...interface{}) {
+		problems = append(problems, Problem{Message: msg, keys: keys})
+	}
+
+	if config.Name == "" {
+		add("missing name")
+	}
+	if msg := checkShell(config.Shell); msg != "" {
+		add(msg, "shell")
+	}
+	if len(config.Commands) == 0 && len(config.Include) == 0 {
+		add("no commands or includes configured")
+	}
+	for _, j := range blankHooks(config.Before) {
+		add(fmt.Sprintf("before hook no. %d is empty", j), "before", j)
+	}
+	for _, j := range blankHooks(config.After) {
+		add(fmt.Sprintf("after hook no. %d is empty", j), "after", j)
+	}
+
+	for i, cmd := range config.Commands {
+		switch {
+		case cmd.Command == "" && len(cmd.Argv) == 0:
+			add(fmt.Sprintf("cmd no. %d is missing command field", i), "commands", i)
+		case cmd.Command != "" && len(cmd.Argv) > 0:
+			add(fmt.Sprintf("cmd no. %d sets both command and argv", i), "commands", i, "argv")
+		case len(cmd.Argv) > 0 && cmd.Argv[0] == "":
+			add(fmt.Sprintf("argv of cmd no. %d is missing a program", i), "commands", i, "argv", 0)
+		}
+		if msg := checkShell(cmd.Shell); msg != "" {
+			add(msg, "commands", i, "shell")
+		}
+		for j, dep := range cmd.DependsOn {
+			if dep.Name == "" {
+				add(fmt.Sprintf("dependency no. %d of cmd no. %d is missing a name", j, i), "commands", i, "depends-on", j)
+			}
+			if !dependencyConditions[dep.Condition] {
+				add(fmt.Sprintf("unknown condition %q, use ready|started|completed-successfully|completed", dep.Condition),
+					"commands", i, "depends-on", j, "condition")
+			}
+		}
+		for _, j := range blankHooks(cmd.Before) {
+			add(fmt.Sprintf("before hook no. %d of cmd no. %d is empty", j, i), "commands", i, "before", j)
+		}
+		for _, j := range blankHooks(cmd.After) {
+			add(fmt.Sprintf("after hook no. %d of cmd no. %d is empty", j, i), "commands", i, "after", j)
+		}
+		if cmd.Watch != nil && cmd.Watch.Debounce != "" {
+			if _, err := time.ParseDuration(cmd.Watch.Debounce); err != nil {
+				add(fmt.Sprintf("invalid watch debounce %q", cmd.Watch.Debounce), "commands", i, "watch", "debounce")
+			}
+		}
+		if cmd.Limits != nil {
+			if _, err := cmd.Limits.CmdsyncLimits(); err != nil {
+				add(fmt.Sprintf("invalid limits of cmd no. %d: %v", i, err), "commands", i, "limits")
+			}
+		}
+	}
+
+	for i, param := range config.Params {
+		if !paramNamePattern.MatchString(param.Name) {
+			add(fmt.Sprintf("param no. %d has invalid name %q, use letters, digits and underscores so templates can refer to it as .Params.<name>", i, param.Name), "params", i, "name")
+		}
+		if reservedFlagNames[param.Name] {
+			add(fmt.Sprintf("param %q is a reserved flag name", param.Name), "params", i, "name")
+		}
+	}
+
+	if !thorough {
+		return problems
+	}
+
+	if checkShell(config.Shell) == "" {
+		if msg := checkExecutable("shell", config.Shell); msg != "" {
+			add(msg, "shell")
+		}
+	}
+
+	seenParams := make(map[string]bool, len(config.Params))
+	for i, param := range config.Params {
+		if seenParams[param.Name] {
+			add(fmt.Sprintf("duplicate param name %q", param.Name), "params", i, "name")
+		}
+		seenParams[param.Name] = true
+	}
+
+	seenNames := make(map[string]bool, len(config.Commands))
+	for i, cmd := range config.Commands {
+		if cmd.Name != "" && seenNames[cmd.Name] {
+			add(fmt.Sprintf("duplicate command name %q", cmd.Name), "commands", i, "name")
+		}
+		seenNames[cmd.Name] = true
+
+		if checkShell(cmd.Shell) == "" {
+			if msg := checkExecutable("shell", cmd.Shell); msg != "" {
+				add(msg, "commands", i, "shell")
+			}
+		}
+		if len(cmd.Argv) > 0 {
+			if msg := checkExecutable("program", cmd.Argv[0]); msg != "" {
+				add(msg, "commands", i, "argv", 0)
+			}
+		}
+
+		if msg := checkRegexp(cmd.ReadyRegexp); msg != "" {
+			add(msg, "commands", i, "ready-regexp")
+		}
+		for j, dep := range cmd.DependsOn {
+			for _, field := range dep.unknownFields {
+				add(fmt.Sprintf("field %s not found in type yaml.Dependency", field), "commands", i, "depends-on", j, field)
+			}
+		}
+		if msg := checkDir(cmd.CmdDir); msg != "" {
+			add(msg, "commands", i, "directory")
+		}
+	}
+
+	for _, profileName := range config.ProfileNames() {
+		for cmdName, override := range config.Profiles[profileName].Commands {
+			if msg := checkRegexp(override.ReadyRegexp); msg != "" {
+				add(msg, "profiles", profileName, "commands", cmdName, "ready-regexp")
+			}
+			dir := resolveDir(filepath.Dir(config.Path), override.CmdDir)
+			if msg := checkDir(dir); msg != "" {
+				add(msg, "profiles", profileName, "commands", cmdName, "directory")
+			}
+		}
+	}
+
+	return problems
+}
+
+// checkResolvedConfig returns the problems of a config that can only be found
+// after its includes are resolved, like missing dependencies and cycles
+func checkResolvedConfig(config OneTerminalConfig, configsByName map[string]OneTerminalConfig) []Problem {
+	resolved, err := includeCommands(config, configsByName, nil, nil)
+	if err != nil {
+		return []Problem{{File: config.Path, Message: err.Error(), keys: []interface{}{"include"}}}
+	}
+
+	var problems []Problem
+	if _, err := resolved.DependencyWaves(); err != nil {
+		p := Problem{File: config.Path, Message: err.Error(), keys: []interface{}{"commands"}}
+		// point at the first of the config's own commands named in the error
+		for i, cmd := range config.Commands {
+			if cmd.Name != "" && strings.Contains(err.Error(), strconv.Quote(cmd.Name)) {
+				p.keys = []interface{}{"commands", i, "depends-on"}
+				break
+			}
+		}
+		problems = append(problems, p)
+	}
+
+	for _, profileName := range resolved.ProfileNames() {
+		if _, err := resolved.ApplyProfile(profileName); err != nil {
+			problems = append(problems, Problem{
+				File:    config.Path,
+				Message: err.Error(),
+				keys:    []interface{}{"profiles", profileName},
+			})
+		}
+	}
+
+	return problems
+}
+
+// blankHooks returns the indexes of hook commands that are empty
+func blankHooks(hooks []string) []int {
+	var blank []int
+	for i, hook := range hooks {
+		if strings.TrimSpace(hook) == "" {
+			blank = append(blank, i)
+		}
+	}
+	return blank
+}
+
+// checkRegexp returns a message if a non-templated pattern does not compile
+func checkRegexp(pattern string) string {
+	if pattern == "" || strings.Contains(pattern, "{{") {
+		return ""
+	}
+	if _, err := regexp.Compile(pattern); err != nil {
+		return fmt.Sprintf("invalid regexp %q: %v", pattern, err)
+	}
+	return ""
+}
+
+// CheckName returns an error if name cannot be used as the name or alias of a
+// new config alongside the existing configs
+func CheckName(name string, configs []OneTerminalConfig) error {
+	if name == "" {
+		return fmt.Errorf("name cannot be empty")
+	}
+	if strings.ContainsAny(name, " \t\n") {
+		return fmt.Errorf("name %q cannot contain whitespace", name)
+	}
+	if reservedNames[name] {
+		return fmt.Errorf("%q is the name of a oneterminal command", name)
+	}
+	for _, config := range configs {
+		if config.Name == name || config.Alias == name {
+			return fmt.Errorf("%q is already used by %s", name, config.Path)
+		}
+	}
+	return nil
+}
+
+// CheckCommand returns an error for the first problem with a command's shell,
+// ready regexp or directory
+func CheckCommand(cmd Command) error {
+	if msg := checkShell(cmd.Shell); msg != "" {
+		return errors.New(msg)
+	}
+	if msg := checkRegexp(cmd.ReadyRegexp); msg != "" {
+		return errors.New(msg)
+	}
+	if msg := checkDir(cmd.CmdDir); msg != "" {
+		return errors.New(msg)
+	}
+	return nil
+}
+
+// checkShell returns a message if a shell name or path contains arguments
+func checkShell(shell string) string {
+	if shell != "" && !shellPattern.MatchString(shell) {
+		return fmt.Sprintf("shell %q contains whitespace, use shell-args for its arguments", shell)
+	}
+	return ""
+}
+
+// checkExecutable returns a message if a non-templated shell or program cannot
+// be found, which would only fail once the command runs
+func checkExecutable(kind, name string) string {
+	if name == "" || strings.Contains(name, "{{") {
+		return ""
+	}
+	if _, err := exec.LookPath(name); err != nil {
+		var execErr *exec.Error
+		if errors.As(err, &execErr) {
+			err = execErr.Err
+		}
+		return fmt.Sprintf("%s %q: %v", kind, name, err)
+	}
+	return ""
+}
+
+// checkDir returns a message if a non-templated directory does not exist
+func checkDir(dir string) string {
+	if dir == "" || strings.Contains(dir, "{{") {
+		return ""
+	}
+	expanded := cmdsync.ExpandDir(dir)
+	info, err := os.Stat(expanded)
+	if err != nil {
+		return fmt.Sprintf("directory %q does not exist", dir)
+	}
+	if !info.IsDir() {
+		return fmt.Sprintf("directory %q is not a directory", dir)
+	}
+	return ""
+}
+
+// lineOf follows a path of mapping keys and sequence indexes from the root of
+// a yaml document and returns the line of the deepest node that is found
+func lineOf(root *yaml.Node, keys []interface{}) int {
+	node := root
+	if node.Kind == yaml.DocumentNode && len(node.Content) > 0 {
+		node = node.Content[0]
+	}
+	line := node.Line
+
+	for _, key := range keys {
+		var next *yaml.Node
+		switch k := key.(type) {
+		case string:
+			if node.Kind != yaml.MappingNode {
+				return line
+			}
+			for i := 0; i+1 < len(node.Content); i += 2 {
+				if node.Content[i].Value == k {
+					line = node.Content[i].Line
+					next = node.Content[i+1]
+					break
+				}
+			}
+		case int:
+			if node.Kind != yaml.SequenceNode || k >= len(node.Content) {
+				return line
+			}
+			next = node.Content[k]
+			line = next.Line
+		}
+		if next == nil {
+			return line
+		}
+		node = next
+	}
+	return line
+}
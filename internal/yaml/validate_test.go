@@ -0,0 +1,210 @@
+package yaml
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestValidate(t *testing.T) {
+	configDir := setupTempDir(t)
+
+	writeConfigFile(t, filepath.Join(configDir, "good.yml"), `name: good
+shell: bash
+commands:
+- name: a
+  command: echo a
+  directory: `+configDir+`
+`)
+	writeConfigFile(t, filepath.Join(configDir, "bad.yml"), `name: bad
+shell: bash -l
+potato: true
+params:
+- name: help
+- name: feature-branch
+commands:
+- name: a
+  command: echo a
+  ready-regexp: "(unclosed"
+  depends-on: [b]
+- name: b
+  command: echo b
+  depends-on: [a]
+  directory: ./does-not-exist
+- name: c
+  command: echo c
+  watch:
+    debounce: soon
+  depends-on:
+  - name: b
+    condition: healthy
+  - name: a
+    colour: red
+- name: c
+  command: echo c again
+  argv: [echo, c]
+  colour: red
+  after: [""]
+  limits:
+    memory: lots
+profiles:
+  missing:
+    disable: [potato]
+`)
+	writeConfigFile(t, filepath.Join(configDir, "shells.yml"), `name: shells
+shell: bsh
+commands:
+- name: a
+  command: echo a
+  shell: /bin/does-not-exist
+- name: b
+  argv: [no-such-program, --help]
+`)
+	writeConfigFile(t, filepath.Join(configDir, "syntax.yml"), "name: syntax\ncommands:\n  - command: [\n")
+	writeConfigFile(t, filepath.Join(configDir, "reserved.yml"), "name: help\ncommands:\n- command: echo\n")
+	writeConfigFile(t, filepath.Join(configDir, "duplicate.yml"), "name: good\ncommands:\n- command: echo\n")
+
+	problems, err := validate(configDir)
+	if err != nil {
+		t.Fatalf("want nil error, got %s", err)
+	}
+
+	var got []string
+	for _, p := range problems {
+		got = append(got, strings.TrimPrefix(p.String(), configDir+string(filepath.Separator)))
+	}
+	want := []string{
+		`bad.yml:2: shell "bash -l" contains whitespace, use shell-args for its arguments`,
+		`bad.yml:3: field potato not found in type yaml.OneTerminalConfig`,
+		`bad.yml:5: param "help" is a reserved flag name`,
+		`bad.yml:6: param no. 1 has invalid name "feature-branch", use letters, digits and underscores so templates can refer to it as .Params.<name>`,
+		`bad.yml:10: invalid regexp "(unclosed": error parsing regexp: missing closing ): ` + "`(unclosed`",
+		`bad.yml:11: dependency cycle between "a", "b", "c"`,
+		`bad.yml:15: directory "` + filepath.Join(configDir, "does-not-exist") + `" does not exist`,
+		`bad.yml:19: invalid watch debounce "soon"`,
+		`bad.yml:22: unknown condition "healthy", use ready|started|completed-successfully|completed`,
+		`bad.yml:24: field colour not found in type yaml.Dependency`,
+		`bad.yml:25: duplicate command name "c"`,
+		`bad.yml:27: cmd no. 3 sets both command and argv`,
+		`bad.yml:28: field colour not found in type yaml.Command`,
+		`bad.yml:29: after hook no. 0 of cmd no. 3 is empty`,
+		`bad.yml:30: invalid limits of cmd no. 3: memory: invalid size "lots", use a number of bytes with an optional K, M, G or T suffix`,
+		`bad.yml:33: profile "missing" references command "potato", which does not exist`,
+		`good.yml:1: duplicate name or alias used: "good" or ""`,
+		`reserved.yml:1: reserved name used: "help" or ""`,
+		`shells.yml:2: shell "bsh": executable file not found in $PATH`,
+		`shells.yml:6: shell "/bin/does-not-exist": stat /bin/does-not-exist: no such file or directory`,
+		`shells.yml:8: program "no-such-program": executable file not found in $PATH`,
+		`syntax.yml:3: did not find expected node content`,
+	}
+	if strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Errorf("want problems\n%s\ngot\n%s", strings.Join(want, "\n"), strings.Join(got, "\n"))
+	}
+}
+
+func TestValidateFile(t *testing.T) {
+	configDir := setupTempDir(t)
+	otherDir := t.TempDir()
+
+	writeConfigFile(t, filepath.Join(configDir, "api.yml"), "name: api\ncommands:\n- command: echo api\n")
+	writeConfigFile(t, filepath.Join(configDir, "copy.yml"), "name: api\ncommands:\n- command: echo copy\n")
+	writeConfigFile(t, filepath.Join(otherDir, "broken.yml"), `name: broken
+commands:
+- name: a
+  command: echo a
+  ready-regexp: "(unclosed"
+  potato: true
+`)
+	writeConfigFile(t, filepath.Join(otherDir, "good.yml"), "name: api\ncommands:\n- command: echo good\n")
+
+	tests := []struct {
+		name     string
+		filename string
+		want     []string
+	}{
+		{
+			name:     "in a config directory",
+			filename: filepath.Join(configDir, "copy.yml"),
+			want:     []string{`copy.yml:1: duplicate name or alias used: "api" or ""`},
+		},
+		{
+			name:     "outside of the config directories",
+			filename: filepath.Join(otherDir, "broken.yml"),
+			want: []string{
+				`broken.yml:5: invalid regexp "(unclosed": error parsing regexp: missing closing ): ` + "`(unclosed`",
+				`broken.yml:6: field potato not found in type yaml.Command`,
+			},
+		},
+		{
+			name:     "outside of the config directories is checked on its own",
+			filename: filepath.Join(otherDir, "good.yml"),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			problems, err := validateFile(configDir, tt.filename)
+			if err != nil {
+				t.Fatalf("want nil error, got %s", err)
+			}
+			var got []string
+			for _, p := range problems {
+				got = append(got, strings.TrimPrefix(p.String(), filepath.Dir(tt.filename)+string(filepath.Separator)))
+			}
+			if strings.Join(got, "\n") != strings.Join(tt.want, "\n") {
+				t.Errorf("want problems\n%s\ngot\n%s", strings.Join(tt.want, "\n"), strings.Join(got, "\n"))
+			}
+		})
+	}
+}
+
+func TestCheckName(t *testing.T) {
+	configs := []OneTerminalConfig{{Name: "api", Alias: "a", Path: "/configs/api.yml"}}
+
+	tests := []struct {
+		name        string
+		wantErrPart string
+	}{
+		{"web", ""},
+		{"", "cannot be empty"},
+		{"my app", "cannot contain whitespace"},
+		{"list", "name of a oneterminal command"},
+		{"api", "already used by /configs/api.yml"},
+		{"a", "already used by /configs/api.yml"},
+	}
+	for _, tt := range tests {
+		err := CheckName(tt.name, configs)
+		if tt.wantErrPart == "" && err != nil {
+			t.Errorf("CheckName(%q) want nil error, got %s", tt.name, err)
+		}
+		if tt.wantErrPart != "" && (err == nil || !strings.Contains(err.Error(), tt.wantErrPart)) {
+			t.Errorf("CheckName(%q) want error containing %q, got %v", tt.name, tt.wantErrPart, err)
+		}
+	}
+}
+
+func TestCheckCommand(t *testing.T) {
+	dir := t.TempDir()
+
+	tests := []struct {
+		name        string
+		cmd         Command
+		wantErrPart string
+	}{
+		{"valid", Command{CmdDir: dir, ReadyRegexp: "ready on port \\d+"}, ""},
+		{"templated", Command{CmdDir: "{{ .Params.dir }}", ReadyRegexp: "{{ .Params.pattern }}"}, ""},
+		{"invalid regexp", Command{ReadyRegexp: "("}, "invalid regexp"},
+		{"missing directory", Command{CmdDir: dir + "/missing"}, "does not exist"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := CheckCommand(tt.cmd)
+			if tt.wantErrPart == "" && err != nil {
+				t.Errorf("want nil error, got %s", err)
+			}
+			if tt.wantErrPart != "" && (err == nil || !strings.Contains(err.Error(), tt.wantErrPart)) {
+				t.Errorf("want error containing %q, got %v", tt.wantErrPart, err)
+			}
+		})
+	}
+}
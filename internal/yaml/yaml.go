@@ -4,154 +4,546 @@ package yaml
 
 import (
 	_ "embed"
+	"errors"
 	"fmt"
 	"os"
 	"path"
 	"path/filepath"
 	"regexp"
+	"strings"
 
-	"gopkg.in/yaml.v2"
+	"gopkg.in/yaml.v3"
 )
 
-// intialized by init(), can be changed for running tests in a tmp directory
-var configDir string
+// configDirOverrides is set by SetConfigDirs, it takes precedence over the
+// environment when searching for config directories
+var configDirOverrides []string
+
+// ConfigPathEnv is a colon separated list of directories to search for configs
+const ConfigPathEnv = "ONETERMINAL_CONFIG_PATH"
 
 //go:embed example.yaml
 var exampleConfig []byte
 
-func init() {
-	// initialize configDir, this allows it to be changed for tests
-	homedir, err := os.UserHomeDir()
-	if err != nil {
-		panic(fmt.Sprintf("getting home directory %v", err))
+// SetConfigDirs overrides the directories that are searched for configs, e.g.
+// with directories passed in via a --config-dir flag.
+func SetConfigDirs(dirs ...string) {
+	configDirOverrides = dirs
+}
+
+// ConfigDirs returns the directories to search for configs, highest precedence
+// first. They are the first of:
+//     1. directories set via SetConfigDirs
+//     2. the ONETERMINAL_CONFIG_PATH environment variable
+//     3. $XDG_CONFIG_HOME/oneterminal
+//     4. ~/.config/oneterminal
+//
+// The directories are not required to exist.
+func ConfigDirs() ([]string, error) {
+	dirs := configDirOverrides
+	if len(dirs) == 0 {
+		dirs = filepath.SplitList(os.Getenv(ConfigPathEnv))
+	}
+	if len(dirs) == 0 {
+		if xdgConfigHome := os.Getenv("XDG_CONFIG_HOME"); xdgConfigHome != "" {
+			dirs = []string{filepath.Join(xdgConfigHome, "oneterminal")}
+		}
+	}
+	if len(dirs) == 0 {
+		homedir, err := os.UserHomeDir()
+		if err != nil {
+			return nil, fmt.Errorf("getting home directory: %w", err)
+		}
+		dirs = []string{filepath.Join(homedir, ".config/oneterminal")}
 	}
-	configDir = filepath.Join(homedir, ".config/oneterminal")
-	if err := os.MkdirAll(configDir, os.ModePerm); err != nil {
-		panic(fmt.Sprintf("making config dir ~/.config/oneterminal %v", err))
+
+	var absDirs []string
+	for _, dir := range dirs {
+		if dir == "" {
+			continue
+		}
+		absDir, err := filepath.Abs(dir)
+		if err != nil {
+			return nil, fmt.Errorf("resolving config directory %q: %w", dir, err)
+		}
+		absDirs = append(absDirs, absDir)
 	}
+	return absDirs, nil
 }
 
 // OneTerminalConfig of all the fields from a yaml config
 type OneTerminalConfig struct {
-	Name     string    `yaml:"name"`
-	Alias    string    `yaml:"alias"`
-	Shell    string    `yaml:"shell"`
-	Short    string    `yaml:"short"`
-	Long     string    `yaml:"long,omitempty"`
-	Commands []Command `yaml:"commands"`
+	Name      string             `yaml:"name" required:"true" desc:"name to run the config with, oneterminal <name>"`
+	Alias     string             `yaml:"alias,omitempty" desc:"alternative name to run the config with"`
+	Shell     string             `yaml:"shell,omitempty" desc:"shell to run commands in by name or path, e.g. bash, sh, fish, nu or pwsh, defaults to zsh"`
+	ShellArgs []string           `yaml:"shell-args,omitempty" desc:"arguments passed to the shell before each command, defaults to [-c]"`
+	Short     string             `yaml:"short,omitempty" desc:"short description shown in help texts"`
+	Long      string             `yaml:"long,omitempty" desc:"long description shown in oneterminal <name> --help"`
+	Include   []string           `yaml:"include,omitempty" desc:"names of configs or paths to config files whose commands are also run"`
+	Params    []Param            `yaml:"params,omitempty" desc:"values passed as flags and substituted into commands via templates"`
+	Profiles  map[string]Profile `yaml:"profiles,omitempty" desc:"variants of the config, selected with --profile"`
+	Before    []string           `yaml:"before,omitempty" desc:"commands run one after another before any command starts, in the config file's directory"`
+	After     []string           `yaml:"after,omitempty" desc:"commands run one after another once all commands have exited, even after ctrl+c"`
+	Commands  []Command          `yaml:"commands" desc:"commands to run"`
+
+	// Path is the file the config was parsed from
+	Path string `yaml:"-"`
+
+	// includedHooks are the configs with hooks that were included, see
+	// HookConfigs
+	includedHooks []OneTerminalConfig
 }
 
 // Command is what will run in one terminal "window"/tab
 type Command struct {
-	Name        string            `yaml:"name"`
-	Command     string            `yaml:"command"`
-	CmdDir      string            `yaml:"directory,omitempty"`
-	Silence     bool              `yaml:"silence,omitempty"`
-	ReadyRegexp string            `yaml:"ready-regexp,omitempty"`
-	DependsOn   []string          `yaml:"depends-on,omitempty"`
-	Environment map[string]string `yaml:"environment,omitempty"`
+	Name        string            `yaml:"name" desc:"prefixes the command's output and is used in depends-on lists"`
+	Command     string            `yaml:"command,omitempty" desc:"the command to run in a shell, required unless argv is set"`
+	Argv        []string          `yaml:"argv,omitempty" desc:"program and arguments to run directly without a shell, instead of command"`
+	Shell       string            `yaml:"shell,omitempty" desc:"shell to run the command, its ready-check and hooks in, defaults to the config's shell"`
+	ShellArgs   []string          `yaml:"shell-args,omitempty" desc:"arguments passed to the shell before the command, defaults to the config's shell-args or [-c]"`
+	CmdDir      string            `yaml:"directory,omitempty" desc:"directory to run the command in, relative to the config file"`
+	Silence     bool              `yaml:"silence,omitempty" desc:"do not print the command's output"`
+	ReadyRegexp string            `yaml:"ready-regexp,omitempty" desc:"regexp the output must match for dependent commands to start"`
+	ReadyCheck  string            `yaml:"ready-check,omitempty" desc:"command that is run every second, dependent commands start once it succeeds"`
+	DependsOn   []Dependency      `yaml:"depends-on,omitempty" desc:"commands that must be ready before this command starts"`
+	Environment map[string]string `yaml:"environment,omitempty" desc:"environment variables to set"`
+	Disabled    bool              `yaml:"disabled,omitempty" desc:"only run the command if a profile enables it"`
+	Watch       *Watch            `yaml:"watch,omitempty" desc:"restart the command when files change"`
+	Before      []string          `yaml:"before,omitempty" desc:"commands run one after another before the command starts, it does not start if one fails"`
+	After       []string          `yaml:"after,omitempty" desc:"commands run one after another after the command exits, even after ctrl+c"`
+	Limits      *Limits           `yaml:"limits,omitempty" desc:"priority and resource limits of the command's processes"`
+
+	// source is the file that declared the command, which can differ from
+	// its config's Path for included commands
+	source string
 }
 
-var isYamlPattern = regexp.MustCompile(".ya?ml$")
+// Source returns the path of the file that declared the command
+func (cmd Command) Source() string {
+	return cmd.source
+}
 
-// ParseAllConfigs parses and returns configs in ~/.config/oneterminal
-func ParseAllConfigs() ([]OneTerminalConfig, error) {
-	// Unmarshal all values from configDir
-	var allConfigs []OneTerminalConfig
-	entries, err := os.ReadDir(configDir)
-	if err != nil {
-		return nil, fmt.Errorf("reading from config directory: %w", err)
+// CommandShell returns the shell that cmd, its ready-check and its hooks run
+// in, and the arguments passed to the shell before each of them. A command's
+// own shell-args are used with its own shell, otherwise the config's are.
+func (c OneTerminalConfig) CommandShell(cmd Command) (string, []string) {
+	shell, args := c.Shell, c.ShellArgs
+	if cmd.Shell != "" {
+		shell, args = cmd.Shell, nil
+	}
+	if len(cmd.ShellArgs) > 0 {
+		args = cmd.ShellArgs
+	}
+	if len(args) == 0 {
+		args = []string{"-c"}
+	}
+	return defaultShell(shell), args
+}
+
+// DependencyNames returns the names of the commands that cmd depends on
+func (cmd Command) DependencyNames() []string {
+	names := make([]string, 0, len(cmd.DependsOn))
+	for _, dep := range cmd.DependsOn {
+		names = append(names, dep.Name)
 	}
+	return names
+}
 
-	for _, e := range entries {
-		if e.IsDir() {
+// Dependency is an entry of a command's depends-on list. It is written as the
+// name of the command, or as a mapping with a condition, e.g.
+//
+//	depends-on:
+//	- db
+//	- name: migrate
+//	  condition: completed-successfully
+type Dependency struct {
+	Name      string `yaml:"name" required:"true" desc:"name of the command to wait for"`
+	Condition string `yaml:"condition,omitempty" enum:"ready,started,completed-successfully,completed" desc:"when the command can start: once the dependency is ready (default), has started, has exited successfully or has exited at all"`
+
+	// unknownFields are keys that are not Dependency fields, which Validate
+	// reports. The decoder's KnownFields setting does not reach UnmarshalYAML.
+	unknownFields []string
+}
+
+// dependencyConditions are the valid Dependency conditions, see the cmdsync
+// Condition constants
+var dependencyConditions = map[string]bool{
+	"":                       true,
+	"ready":                  true,
+	"started":                true,
+	"completed-successfully": true,
+	"completed":              true,
+}
+
+func (d *Dependency) UnmarshalYAML(value *yaml.Node) error {
+	if value.Kind == yaml.ScalarNode {
+		*d = Dependency{Name: value.Value}
+		return nil
+	}
+	if value.Kind != yaml.MappingNode {
+		return &yaml.TypeError{Errors: []string{fmt.Sprintf(
+			"line %d: cannot unmarshal %s into a depends-on entry", value.Line, value.ShortTag())}}
+	}
+
+	*d = Dependency{}
+	for i := 0; i+1 < len(value.Content); i += 2 {
+		key, val := value.Content[i], value.Content[i+1]
+		var field *string
+		switch key.Value {
+		case "name":
+			field = &d.Name
+		case "condition":
+			field = &d.Condition
+		default:
+			d.unknownFields = append(d.unknownFields, key.Value)
 			continue
 		}
-		if !isYamlPattern.MatchString(e.Name()) {
-			continue
+		if err := val.Decode(field); err != nil {
+			return err
 		}
+	}
+	return nil
+}
+
+func (d Dependency) MarshalYAML() (interface{}, error) {
+	if d.Condition == "" {
+		return d.Name, nil
+	}
+	// a distinct type, so MarshalYAML is not called again
+	type dependency Dependency
+	return dependency{Name: d.Name, Condition: d.Condition}, nil
+}
 
-		filename := path.Join(configDir, e.Name())
+// Watch configures the files that restart a command when they change
+type Watch struct {
+	Paths             []string `yaml:"paths,omitempty" desc:"files and directories to watch, relative to the command's directory (default the command's directory)"`
+	Include           []string `yaml:"include,omitempty" desc:"glob patterns that changed files must match, e.g. *.go"`
+	Exclude           []string `yaml:"exclude,omitempty" desc:"glob patterns of files and directories to ignore, e.g. node_modules"`
+	Debounce          string   `yaml:"debounce,omitempty" desc:"how long changes must settle before restarting, e.g. 500ms (default 300ms)"`
+	RestartDependents bool     `yaml:"restart-dependents,omitempty" desc:"also restart the commands that depend on this one"`
+}
 
-		bytes, err := os.ReadFile(filename)
-		if err != nil {
-			return nil, fmt.Errorf("reading file %s: %w", filename, err)
+// Limits restrict the priority and resources of a command's processes, see
+// cmdsync.Limits
+type Limits struct {
+	Nice         int     `yaml:"nice,omitempty" desc:"niceness from -20 (highest priority) to 19, lowering it needs root"`
+	NoFile       uint64  `yaml:"nofile,omitempty" desc:"max open files of each process"`
+	AddressSpace string  `yaml:"address-space,omitempty" desc:"max virtual memory of each process, e.g. 8G"`
+	CPUTime      string  `yaml:"cpu-time,omitempty" desc:"max CPU time of each process, e.g. 30m"`
+	Memory       string  `yaml:"memory,omitempty" desc:"max memory of all the command's processes together, e.g. 2G, needs cgroup v2"`
+	CPUs         float64 `yaml:"cpus,omitempty" desc:"max CPUs used by all the command's processes together, e.g. 1.5, needs cgroup v2"`
+}
+
+// Param is a value that is passed to a config as a command line flag, e.g.
+// --branch feature-x, and substituted into its commands as {{ .Params.branch }}
+type Param struct {
+	Name        string `yaml:"name" required:"true" desc:"flag name, used in templates as {{ .Params.<name> }}"`
+	Default     string `yaml:"default,omitempty" desc:"value used when the flag is not passed"`
+	Description string `yaml:"description,omitempty" desc:"help text of the flag"`
+	Required    bool   `yaml:"required,omitempty" desc:"error if the param has no value"`
+}
+
+var isYamlPattern = regexp.MustCompile(".ya?ml$")
+
+// localConfigName is the name of a project-local config file (with a .yml or
+// .yaml extension) or directory of config files. They are discovered by
+// walking up from the current working directory, so configs can be committed
+// alongside the repos they start.
+const localConfigName = ".oneterminal"
+
+// ParseAllConfigs parses and returns configs in the ConfigDirs and any
+// project-local configs found in the current working directory or its parents.
+//
+// Project-local configs take precedence over global configs, and configs that
+// are closer to the working directory take precedence over those further up.
+// Global configs follow the order of ConfigDirs.
+// A config that shares its name with a higher precedence config is skipped.
+//
+// Config files that cannot be loaded, e.g. because of a syntax error, a name
+// collision or a broken include, are also skipped so one broken file does not
+// hide every other config. They are returned as warnings. The returned error
+// is only set if the config directories themselves cannot be read.
+func ParseAllConfigs() ([]OneTerminalConfig, []error, error) {
+	wd, err := os.Getwd()
+	if err != nil {
+		return nil, nil, fmt.Errorf("getting working directory: %w", err)
+	}
+	return parseAllConfigs(wd)
+}
+
+func parseAllConfigs(workDir string) ([]OneTerminalConfig, []error, error) {
+	sources, err := configSources(workDir)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var allConfigs []OneTerminalConfig
+	var warnings []error
+	shadowed := make(map[string]bool)
+	for _, filenames := range sources {
+		var sourceConfigs []OneTerminalConfig
+		for _, filename := range filenames {
+			oneTermConfig, err := parseConfigFile(filename)
+			if err != nil {
+				warnings = append(warnings, err)
+				continue
+			}
+			if shadowed[oneTermConfig.Name] {
+				continue
+			}
+			sourceConfigs = append(sourceConfigs, oneTermConfig)
 		}
-		var oneTermConfig OneTerminalConfig
-		err = yaml.Unmarshal(bytes, &oneTermConfig)
-		if err != nil {
-			return nil, fmt.Errorf("unmarshalling file %s: %w", filename, err)
+
+		for _, config := range sourceConfigs {
+			shadowed[config.Name] = true
+		}
+		allConfigs = append(allConfigs, sourceConfigs...)
+	}
+
+	allConfigs, problems := nameCollisions(allConfigs)
+	for _, p := range problems {
+		warnings = append(warnings, errors.New(p.String()))
+	}
+
+	allConfigs, includeErrs := resolveIncludes(allConfigs)
+	warnings = append(warnings, includeErrs...)
+
+	return allConfigs, warnings, nil
+}
+
+// configSources returns the project-local config files found from workDir and
+// the config files in ConfigDirs. Each element is a set of config files of the
+// same precedence, highest precedence first.
+func configSources(workDir string) ([][]string, error) {
+	sources, err := findLocalConfigs(workDir)
+	if err != nil {
+		return nil, err
+	}
+	configDirs, err := ConfigDirs()
+	if err != nil {
+		return nil, err
+	}
+	for _, dir := range configDirs {
+		globalFiles, err := yamlFilesInDir(dir)
+		if os.IsNotExist(err) {
+			continue
 		}
-		err = validateConfig(oneTermConfig)
 		if err != nil {
-			return nil, fmt.Errorf("invalid config from %q: %w", filename, err)
+			return nil, fmt.Errorf("reading from config directory: %w", err)
 		}
+		sources = append(sources, globalFiles)
+	}
+	return sources, nil
+}
 
-		allConfigs = append(allConfigs, oneTermConfig)
+// parseConfigFile unmarshals and validates a single config file. Relative
+// command directories are resolved against the config file's directory.
+func parseConfigFile(filename string) (OneTerminalConfig, error) {
+	bytes, err := os.ReadFile(filename)
+	if err != nil {
+		return OneTerminalConfig{}, fmt.Errorf("reading file %s: %w", filename, err)
+	}
+	var oneTermConfig OneTerminalConfig
+	err = yaml.Unmarshal(bytes, &oneTermConfig)
+	if err != nil {
+		return OneTerminalConfig{}, fmt.Errorf("unmarshalling file %s: %w", filename, err)
+	}
+	err = validateConfig(oneTermConfig)
+	if err != nil {
+		return OneTerminalConfig{}, fmt.Errorf("invalid config from %q: %w", filename, err)
 	}
 
-	return allConfigs, nil
+	oneTermConfig.setPath(filename)
+
+	return oneTermConfig, nil
 }
 
-// non-exhaustive validation, checks for required fields
-func validateConfig(config OneTerminalConfig) error {
-	if config.Name == "" {
-		return fmt.Errorf("missing name")
+// setPath records the file a config was parsed from and resolves its commands'
+// relative directories against the file's directory
+func (c *OneTerminalConfig) setPath(filename string) {
+	c.Path = filename
+	for i, cmd := range c.Commands {
+		c.Commands[i].source = filename
+		c.Commands[i].CmdDir = resolveDir(filepath.Dir(filename), cmd.CmdDir)
 	}
-	if regexp.MustCompile("`^(zsh|sh|bash)$`").MatchString(config.Shell) {
-		return fmt.Errorf("%s shell not supported", config.Shell)
+}
+
+// resolveDir joins a relative directory onto baseDir. Directories starting
+// with '~' or an environment variable are left for cmdsync to expand, and
+// directories starting with a template are resolved after rendering.
+func resolveDir(baseDir, dir string) string {
+	if dir == "" || filepath.IsAbs(dir) || dir[0] == '~' || dir[0] == '$' ||
+		strings.HasPrefix(dir, "{{") {
+		return dir
 	}
-	if len(config.Commands) == 0 {
-		return fmt.Errorf("no commands configured")
+	return filepath.Join(baseDir, dir)
+}
+
+// findLocalConfigs walks up from dir to the filesystem root looking for
+// .oneterminal.yml/.oneterminal.yaml files and .oneterminal/ directories. The
+// config files of each directory are returned as one element, nearest first.
+func findLocalConfigs(dir string) ([][]string, error) {
+	var found [][]string
+	for {
+		var filenames []string
+		for _, ext := range []string{".yml", ".yaml"} {
+			filename := filepath.Join(dir, localConfigName+ext)
+			if info, err := os.Stat(filename); err == nil && !info.IsDir() {
+				filenames = append(filenames, filename)
+			}
+		}
+
+		localDir := filepath.Join(dir, localConfigName)
+		if info, err := os.Stat(localDir); err == nil && info.IsDir() {
+			dirFiles, err := yamlFilesInDir(localDir)
+			if err != nil {
+				return nil, fmt.Errorf("reading from local config directory: %w", err)
+			}
+			filenames = append(filenames, dirFiles...)
+		}
+
+		if len(filenames) > 0 {
+			found = append(found, filenames)
+		}
+
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return found, nil
+		}
+		dir = parent
+	}
+}
+
+// yamlFilesInDir returns the paths of all yaml files directly inside dir
+func yamlFilesInDir(dir string) ([]string, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
 	}
 
-	for i, cmd := range config.Commands {
-		if cmd.Command == "" {
-			return fmt.Errorf("cmd no. %d is missing command field", i)
+	var filenames []string
+	for _, e := range entries {
+		if e.IsDir() {
+			continue
 		}
+		if !isYamlPattern.MatchString(e.Name()) {
+			continue
+		}
+		filenames = append(filenames, path.Join(dir, e.Name()))
 	}
+	return filenames, nil
+}
 
+// non-exhaustive validation, checks for required fields. See Validate for a
+// thorough check of all config files
+func validateConfig(config OneTerminalConfig) error {
+	if problems := checkConfig(config, false); len(problems) > 0 {
+		return errors.New(problems[0].Message)
+	}
 	return nil
 }
 
-// HasNameCollisions returns an error if multiple configs have the same name,
-// alias or one of the reserved names (for built in oneterminal cmds like help)
-func HasNameCollisions(configs []OneTerminalConfig) error {
-	reservedNames := map[string]bool{
-		"completion": true,
-		"example":    true,
-		"help":       true,
-		"list":       true,
-		"ls":         true,
-		"update":     true,
-	}
+// paramNamePattern matches names that are valid command line flags and can be
+// used as keys in templates, e.g. .Params.feature_branch, which rules out "-"
+var paramNamePattern = regexp.MustCompile(`^[a-zA-Z][a-zA-Z0-9_]*$`)
 
+// reservedFlagNames are flags of oneterminal that params cannot shadow
+var reservedFlagNames = map[string]bool{
+	"config-dir":   true,
+	"dry-run":      true,
+	"except":       true,
+	"help":         true,
+	"metrics-addr": true,
+	"no-reload":    true,
+	"only":         true,
+	"profile":      true,
+}
+
+// reservedNames are the names of built in oneterminal commands
+var reservedNames = map[string]bool{
+	"completion": true,
+	"describe":   true,
+	"edit":       true,
+	"example":    true,
+	"export":     true,
+	"graph":      true,
+	"help":       true,
+	"import":     true,
+	"lint":       true,
+	"list":       true,
+	"ls":         true,
+	"new":        true,
+	"schema":     true,
+	"status":     true,
+	"update":     true,
+	"validate":   true,
+	"version":    true,
+}
+
+// nameCollisions returns a problem for every config with a name or alias that
+// is reserved (for built in oneterminal cmds like help) or used by an earlier
+// config, along with the configs that have no collisions
+func nameCollisions(configs []OneTerminalConfig) ([]OneTerminalConfig, []Problem) {
+	var kept []OneTerminalConfig
+	var problems []Problem
 	allNames := make(map[string]bool)
 	for _, config := range configs {
 		if allNames[config.Name] || allNames[config.Alias] {
-			return fmt.Errorf("duplicate name or alias used: %q or %q", config.Name, config.Alias)
+			problems = append(problems, Problem{
+				File:    config.Path,
+				Message: fmt.Sprintf("duplicate name or alias used: %q or %q", config.Name, config.Alias),
+				keys:    []interface{}{"name"},
+			})
+			continue
 		}
 
 		if reservedNames[config.Name] || reservedNames[config.Alias] {
-			return fmt.Errorf("reserved name used: %q or %q", config.Name, config.Alias)
+			problems = append(problems, Problem{
+				File:    config.Path,
+				Message: fmt.Sprintf("reserved name used: %q or %q", config.Name, config.Alias),
+				keys:    []interface{}{"name"},
+			})
+			continue
 		}
 
 		allNames[config.Name] = true
 		if config.Alias != "" {
 			allNames[config.Alias] = true
 		}
+		kept = append(kept, config)
 	}
 
-	return nil
+	return kept, problems
 }
 
-// WriteExampleConfig makes an example oneterminal yaml config at
-// ~/.config/oneterminal/example.yml with comments describing each field
-func WriteExampleConfig(filename string) error {
-	err := os.WriteFile(path.Join(configDir, filename), exampleConfig, os.ModePerm)
+// NewConfigPath returns the path of filename in the highest precedence config
+// directory, creating the directory if it does not exist.
+func NewConfigPath(filename string) (string, error) {
+	configDirs, err := ConfigDirs()
 	if err != nil {
-		return fmt.Errorf("writing to example config file: %w", err)
+		return "", err
+	}
+	if len(configDirs) == 0 {
+		return "", fmt.Errorf("no config directories configured")
+	}
+	if err := os.MkdirAll(configDirs[0], os.ModePerm); err != nil {
+		return "", fmt.Errorf("making config directory: %w", err)
 	}
+	return path.Join(configDirs[0], filename), nil
+}
 
-	return nil
+// WriteExampleConfig makes an example oneterminal yaml config in the highest
+// precedence config directory with comments describing each field. It returns
+// the path of the written file.
+func WriteExampleConfig(filename string) (string, error) {
+	examplePath, err := NewConfigPath(filename)
+	if err != nil {
+		return "", err
+	}
+	err = os.WriteFile(examplePath, exampleConfig, os.ModePerm)
+	if err != nil {
+		return "", fmt.Errorf("writing to example config file: %w", err)
+	}
+
+	return examplePath, nil
 }
@@ -3,12 +3,21 @@ package yaml
 import (
 	"os"
 	"path"
+	"path/filepath"
+	"reflect"
+	"strings"
 	"testing"
+
+	"gopkg.in/yaml.v3"
 )
 
-// helper function that sets the configDir variable to the default temp directory
-func setupTempDir(t *testing.T) {
-	configDir = os.TempDir()
+// helper function that points the config directories at a new temp directory
+// until the test finishes
+func setupTempDir(t *testing.T) string {
+	configDir := t.TempDir()
+	SetConfigDirs(configDir)
+	t.Cleanup(func() { SetConfigDirs() })
+	return configDir
 }
 
 // testing utility function
@@ -21,17 +30,17 @@ func getFileContents(t *testing.T, path string) string {
 }
 
 func TestWriteExampleConfig(t *testing.T) {
-	setupTempDir(t)
+	configDir := setupTempDir(t)
 
 	filename := "oneterminal-example-with-instructions.yml"
-	err := WriteExampleConfig(filename)
+	filepath, err := WriteExampleConfig(filename)
 	if err != nil {
 		t.Errorf("want nil error, got %s", err)
 	}
-
-	filepath := path.Join(configDir, filename)
+	if want := path.Join(configDir, filename); filepath != want {
+		t.Errorf("want example written to %q, got %q", want, filepath)
+	}
 	t.Logf("Wrote to temp file %s\n", filepath)
-	defer os.Remove(filepath)
 
 	fileContents := getFileContents(t, filepath)
 
@@ -49,14 +58,13 @@ func TestParseAllConfigs(t *testing.T) {
 
 	// add an example config
 	filename := "oneterminal-example-parse-configs.yml"
-	WriteExampleConfig(filename)
-	t.Logf("Wrote to temp file %s\n", path.Join(configDir, filename))
-	defer os.Remove(path.Join(configDir, filename))
+	examplePath, _ := WriteExampleConfig(filename)
+	t.Logf("Wrote to temp file %s\n", examplePath)
 
-	configs, err := ParseAllConfigs()
-	if err != nil {
+	configs, warnings, err := ParseAllConfigs()
+	if err != nil || len(warnings) > 0 {
 		t.Log("Relies on MakeExampleConfigFromStruct, ensure it is working.")
-		t.Errorf("want nil error, got %s", err)
+		t.Errorf("want nil error and no warnings, got %v and %v", err, warnings)
 	}
 	if len(configs) < 1 {
 		t.Log("Relies on MakeExampleConfigFromStruct, ensure it is working.")
@@ -87,3 +95,214 @@ func TestParseAllConfigs(t *testing.T) {
 		}
 	}
 }
+
+func TestParseAllConfigs_LocalConfigs(t *testing.T) {
+	root := t.TempDir()
+	configDir := filepath.Join(root, "global")
+	SetConfigDirs(configDir)
+	t.Cleanup(func() { SetConfigDirs() })
+	projectDir := filepath.Join(root, "project")
+	workDir := filepath.Join(projectDir, "service")
+
+	writeConfigFile(t, filepath.Join(configDir, "api.yml"), "name: api\nshort: global\ncommands:\n- command: echo global\n")
+	writeConfigFile(t, filepath.Join(configDir, "db.yml"), "name: db\nshort: global\ncommands:\n- command: echo db\n")
+	writeConfigFile(t, filepath.Join(projectDir, ".oneterminal.yml"),
+		"name: api\nshort: project\ncommands:\n- command: echo project\n  directory: ./api\n")
+	writeConfigFile(t, filepath.Join(workDir, ".oneterminal", "web.yaml"),
+		"name: web\nshort: service\ncommands:\n- command: echo web\n  directory: $HOME\n")
+
+	configs, warnings, err := parseAllConfigs(workDir)
+	if err != nil || len(warnings) > 0 {
+		t.Fatalf("want nil error and no warnings, got %v and %v", err, warnings)
+	}
+
+	byName := map[string]OneTerminalConfig{}
+	var names []string
+	for _, config := range configs {
+		byName[config.Name] = config
+		names = append(names, config.Name)
+	}
+	if want := "web,api,db"; strings.Join(names, ",") != want {
+		t.Errorf("want configs in precedence order %q, got %q", want, strings.Join(names, ","))
+	}
+	if got := byName["api"].Short; got != "project" {
+		t.Errorf("want project-local api config to shadow global config, got short %q", got)
+	}
+	if got, want := byName["api"].Commands[0].CmdDir, filepath.Join(projectDir, "api"); got != want {
+		t.Errorf("want relative directory resolved to %q, got %q", want, got)
+	}
+	if got := byName["web"].Commands[0].CmdDir; got != "$HOME" {
+		t.Errorf("want env var directory left unexpanded, got %q", got)
+	}
+}
+
+func TestConfigDirs(t *testing.T) {
+	t.Cleanup(func() { SetConfigDirs() })
+	for _, key := range []string{ConfigPathEnv, "XDG_CONFIG_HOME", "HOME"} {
+		key, value := key, os.Getenv(key)
+		defer os.Setenv(key, value)
+	}
+	os.Setenv("HOME", "/home/potato")
+
+	tests := []struct {
+		name       string
+		overrides  []string
+		configPath string
+		xdgHome    string
+		want       []string
+	}{
+		{
+			name: "defaults to home directory",
+			want: []string{"/home/potato/.config/oneterminal"},
+		},
+		{
+			name:    "XDG_CONFIG_HOME",
+			xdgHome: "/xdg",
+			want:    []string{"/xdg/oneterminal"},
+		},
+		{
+			name:       "config path env var is a list",
+			configPath: "/team/configs:/my/configs",
+			xdgHome:    "/xdg",
+			want:       []string{"/team/configs", "/my/configs"},
+		},
+		{
+			name:       "overrides take precedence",
+			overrides:  []string{"/flag/configs"},
+			configPath: "/team/configs",
+			want:       []string{"/flag/configs"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			SetConfigDirs(tt.overrides...)
+			os.Setenv(ConfigPathEnv, tt.configPath)
+			os.Setenv("XDG_CONFIG_HOME", tt.xdgHome)
+
+			got, err := ConfigDirs()
+			if err != nil {
+				t.Fatalf("want nil error, got %s", err)
+			}
+			if strings.Join(got, ",") != strings.Join(tt.want, ",") {
+				t.Errorf("want %q, got %q", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestParseAllConfigs_BrokenConfigs(t *testing.T) {
+	configDir := setupTempDir(t)
+
+	writeConfigFile(t, filepath.Join(configDir, "good.yml"), "name: good\ncommands:\n- command: echo good\n")
+	writeConfigFile(t, filepath.Join(configDir, "syntax.yml"), "name: syntax\ncommands: [\n")
+	writeConfigFile(t, filepath.Join(configDir, "shell.yml"), "name: shell\nshell: bash -l\ncommands:\n- command: echo bash\n")
+	writeConfigFile(t, filepath.Join(configDir, "reserved.yml"), "name: help\ncommands:\n- command: echo help\n")
+	writeConfigFile(t, filepath.Join(configDir, "twin.yml"), "name: twin\nalias: good\ncommands:\n- command: echo twin\n")
+
+	configs, warnings, err := parseAllConfigs(configDir)
+	if err != nil {
+		t.Fatalf("want nil error, got %s", err)
+	}
+	if len(configs) != 1 || configs[0].Name != "good" {
+		t.Errorf("want only the good config to be loaded, got %d configs", len(configs))
+	}
+
+	wantWarningParts := []string{
+		"shell.yml",
+		"syntax.yml",
+		"reserved.yml: reserved name used",
+		"twin.yml: duplicate name or alias used",
+	}
+	if len(warnings) != len(wantWarningParts) {
+		t.Fatalf("want %d warnings, got %d: %v", len(wantWarningParts), len(warnings), warnings)
+	}
+	for i, part := range wantWarningParts {
+		if !strings.Contains(warnings[i].Error(), part) {
+			t.Errorf("want warning %d to contain %q, got %q", i, part, warnings[i])
+		}
+	}
+}
+
+func TestDependency_YAML(t *testing.T) {
+	var cmd Command
+	err := yaml.Unmarshal([]byte(`command: go run .
+depends-on:
+- db
+- name: migrate
+  condition: completed-successfully
+`), &cmd)
+	if err != nil {
+		t.Fatalf("want nil error, got %s", err)
+	}
+	want := []Dependency{{Name: "db"}, {Name: "migrate", Condition: "completed-successfully"}}
+	if !reflect.DeepEqual(cmd.DependsOn, want) {
+		t.Errorf("want depends-on %v, got %v", want, cmd.DependsOn)
+	}
+
+	// dependencies without conditions are written as names
+	out, err := Marshal(cmd)
+	if err != nil {
+		t.Fatalf("want nil error, got %s", err)
+	}
+	wantOut := `name: ""
+command: go run .
+depends-on:
+  - db
+  - name: migrate
+    condition: completed-successfully
+`
+	if string(out) != wantOut {
+		t.Errorf("want marshalled command\n%s\ngot\n%s", wantOut, out)
+	}
+
+	err = yaml.Unmarshal([]byte("depends-on:\n- [db]\n"), &cmd)
+	if err == nil || !strings.Contains(err.Error(), "line 2: cannot unmarshal !!seq into a depends-on entry") {
+		t.Errorf("want error for a nested list, got %v", err)
+	}
+}
+
+func TestOneTerminalConfig_CommandShell(t *testing.T) {
+	tests := []struct {
+		name      string
+		config    OneTerminalConfig
+		cmd       Command
+		wantShell string
+		wantArgs  []string
+	}{
+		{
+			name:      "defaults",
+			wantShell: "zsh",
+			wantArgs:  []string{"-c"},
+		},
+		{
+			name:      "config shell and args",
+			config:    OneTerminalConfig{Shell: "pwsh", ShellArgs: []string{"-NoProfile", "-Command"}},
+			wantShell: "pwsh",
+			wantArgs:  []string{"-NoProfile", "-Command"},
+		},
+		{
+			name:      "command shell does not use the config's args",
+			config:    OneTerminalConfig{Shell: "pwsh", ShellArgs: []string{"-NoProfile", "-Command"}},
+			cmd:       Command{Shell: "fish"},
+			wantShell: "fish",
+			wantArgs:  []string{"-c"},
+		},
+		{
+			name:      "command args with the config's shell",
+			config:    OneTerminalConfig{Shell: "bash"},
+			cmd:       Command{ShellArgs: []string{"-l", "-c"}},
+			wantShell: "bash",
+			wantArgs:  []string{"-l", "-c"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			shell, args := tt.config.CommandShell(tt.cmd)
+			if shell != tt.wantShell || !reflect.DeepEqual(args, tt.wantArgs) {
+				t.Errorf("want %s %q, got %s %q", tt.wantShell, tt.wantArgs, shell, args)
+			}
+		})
+	}
+}